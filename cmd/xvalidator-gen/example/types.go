@@ -0,0 +1,66 @@
+// Package example shows the Validate methods generated by xvalidator-gen,
+// see xvalidator_gen.go.
+package example
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ccbhj/xvalidator"
+)
+
+//go:generate go run github.com/ccbhj/xvalidator/cmd/xvalidator-gen
+
+// the validators and constants must be registered before the structs are
+// registered in the init of xvalidator_gen.go
+var _ = registerValidators()
+
+func registerValidators() bool {
+	xvalidator.RegisterValidatorFactory("sku", xvalidator.Func(func(s string) error {
+		if !strings.HasPrefix(s, "SKU-") {
+			return xvalidator.ValidatorError{Reason: "invalid sku"}
+		}
+		return nil
+	}))
+	xvalidator.RegisterConstStr("CURRENCY", "USD")
+	return true
+}
+
+type Address struct {
+	Street  string `xvldt:"not_empty()"`
+	Zip     string `xvldt:"len(5), regex('^[0-9]+$')"`
+	Country string `xvldt:"omitempty(), srange('CN', 'US', 'CN')"`
+}
+
+type Item struct {
+	SKU      string `xvldt:"sku()"`
+	Quantity int    `xvldt:"min(1), max(100)"`
+}
+
+type Order struct {
+	ID       string   `xvldt:"required(), len(8)"`
+	Status   uint8    `xvldt:"irange(1, 2, 3, 1)"`
+	Currency string   `xvldt:"srange(CURRENCY, 'EUR')"`
+	Discount int      `xvldt:"omitempty(), max(50)"`
+	Note     string   `xvldt:"update: not_empty()"`
+	Address  Address  `xvldt:"strct()"`
+	Billing  *Address `xvldt:"strct()"`
+	Items    []Item   `xvldt:"strct()"`
+	Internal string
+}
+
+// XValidate runs after the fields of Address pass
+func (a Address) XValidate() error {
+	if a.Country == "CN" && strings.HasPrefix(a.Zip, "0") {
+		return xvalidator.FieldError("Zip", xvalidator.ValidatorError{Reason: "invalid zip of CN"})
+	}
+	return nil
+}
+
+// XValidateCtx runs after the fields of Order pass
+func (o *Order) XValidateCtx(ctx context.Context) error {
+	if o.Status == 3 && o.Billing == nil {
+		return xvalidator.FieldError("Billing", xvalidator.ValidatorError{Reason: "required when shipped"})
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package example
+
+import (
+	"testing"
+
+	"github.com/ccbhj/xvalidator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratedValidate(t *testing.T) {
+	valid := func() Order {
+		return Order{
+			ID:       "ORDER-01",
+			Status:   1,
+			Currency: "USD",
+			Address:  Address{Street: "Main St", Zip: "10001", Country: "US"},
+			Items:    []Item{{SKU: "SKU-1", Quantity: 1}},
+		}
+	}
+	cases := map[string]func(o *Order){
+		"valid":            func(o *Order) {},
+		"missing id":       func(o *Order) { o.ID = "" },
+		"short id":         func(o *Order) { o.ID = "ORDER" },
+		"bad status":       func(o *Order) { o.Status = 4 },
+		"const currency":   func(o *Order) { o.Currency = "EUR" },
+		"bad currency":     func(o *Order) { o.Currency = "CNY" },
+		"omitted discount": func(o *Order) { o.Discount = 0 },
+		"big discount":     func(o *Order) { o.Discount = 51 },
+		"negative":         func(o *Order) { o.Discount = -1 },
+		"grouped note":     func(o *Order) { o.Note = " " },
+		"empty street":     func(o *Order) { o.Address.Street = "  " },
+		"short zip":        func(o *Order) { o.Address.Zip = "1000" },
+		"bad zip":          func(o *Order) { o.Address.Zip = "1000a" },
+		"no country":       func(o *Order) { o.Address.Country = "" },
+		"bad country":      func(o *Order) { o.Address.Country = "JP" },
+		"bad billing":      func(o *Order) { o.Billing = &Address{Street: "Main St", Zip: "1"} },
+		"good billing":     func(o *Order) { o.Billing = &Address{Street: "Main St", Zip: "10001"} },
+		"bad sku":          func(o *Order) { o.Items = append(o.Items, Item{SKU: "1", Quantity: 1}) },
+		"no quantity":      func(o *Order) { o.Items[0].Quantity = 0 },
+		"many quantity":    func(o *Order) { o.Items[0].Quantity = 101 },
+		"no items":         func(o *Order) { o.Items = nil },
+		"errors":           func(o *Order) { o.ID, o.Address.Zip = "", "" },
+		"bad cn zip":       func(o *Order) { o.Address.Country, o.Address.Zip = "CN", "01234" },
+		"bad cn billing":   func(o *Order) { o.Billing = &Address{Street: "a", Zip: "01234", Country: "CN"} },
+		"no billing":       func(o *Order) { o.Status = 3 },
+		"hooks":            func(o *Order) { o.Status, o.Address.Country, o.Address.Zip = 3, "CN", "01234" },
+	}
+	for name, modify := range cases {
+		o := valid()
+		modify(&o)
+		err := o.Validate()
+		assert.Equal(t, xvalidator.ValidateStruct(&o), err, name)
+
+		copied := o
+		assert.Equal(t, err, xvalidator.ValidateStruct(copied), name)
+	}
+
+	assert.Equal(t, xvalidator.ErrInvalidStruct, (*Order)(nil).Validate())
+	o := valid()
+	assert.Nil(t, o.Validate())
+	o.ID = ""
+	assert.Equal(t, "validate fail for field ID: required", o.Validate().Error())
+	o = valid()
+	o.Address.Zip = "1"
+	assert.Equal(t, "validate fail for field Address.Zip: invalid string length", o.Validate().Error())
+	o = valid()
+	o.Status = 3
+	assert.Equal(t, "validate fail for field Billing: required when shipped", o.Validate().Error())
+}
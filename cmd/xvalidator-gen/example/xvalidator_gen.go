@@ -0,0 +1,100 @@
+// Code generated by xvalidator-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ccbhj/xvalidator"
+)
+
+var xvldtPatterns = [...]*regexp.Regexp{
+	regexp.MustCompile("^[0-9]+$"),
+}
+
+func init() {
+	if err := xvalidator.RegisterStructs(Address{}, Item{}, Order{}); err != nil {
+		panic(err)
+	}
+}
+
+// Validate validates the fields of Address like xvalidator.ValidateStruct.
+func (x *Address) Validate() error {
+	if x == nil {
+		return xvalidator.ErrInvalidStruct
+	}
+	if strings.TrimSpace(x.Street) == "" {
+		return xvalidator.FieldError("Street", xvalidator.ValidatorError{Reason: "empty string"})
+	}
+	if uint64(len(x.Zip)) != 5 {
+		return xvalidator.FieldError("Zip", xvalidator.ValidatorError{Reason: "invalid string length"})
+	}
+	if !xvldtPatterns[0].MatchString(x.Zip) {
+		return xvalidator.FieldError("Zip", xvalidator.ValidatorError{Reason: "string not match pattern"})
+	}
+	if x.Country != "" {
+		switch x.Country {
+		case "CN", "US":
+		default:
+			return xvalidator.FieldError("Country", xvalidator.ValidatorError{Reason: "invalid value"})
+		}
+	}
+	return xvalidator.CallHook(x)
+}
+
+// Validate validates the fields of Item like xvalidator.ValidateStruct.
+func (x *Item) Validate() error {
+	if x == nil {
+		return xvalidator.ErrInvalidStruct
+	}
+	if err := xvalidator.ValidateFields(x, "SKU"); err != nil {
+		return err
+	}
+	if uint64(x.Quantity) < 1 {
+		return xvalidator.FieldError("Quantity", xvalidator.ValidatorError{Reason: "out of range"})
+	}
+	if uint64(x.Quantity) > 100 {
+		return xvalidator.FieldError("Quantity", xvalidator.ValidatorError{Reason: "out of range"})
+	}
+	return nil
+}
+
+// Validate validates the fields of Order like xvalidator.ValidateStruct.
+func (x *Order) Validate() error {
+	if x == nil {
+		return xvalidator.ErrInvalidStruct
+	}
+	if x.ID == "" {
+		return xvalidator.FieldError("ID", xvalidator.ValidatorError{Reason: "required"})
+	}
+	if uint64(len(x.ID)) != 8 {
+		return xvalidator.FieldError("ID", xvalidator.ValidatorError{Reason: "invalid string length"})
+	}
+	switch uint64(x.Status) {
+	case 1, 2, 3:
+	default:
+		return xvalidator.FieldError("Status", xvalidator.ValidatorError{Reason: "invalid value"})
+	}
+	if err := xvalidator.ValidateFields(x, "Currency"); err != nil {
+		return err
+	}
+	if x.Discount != 0 {
+		if uint64(x.Discount) > 50 {
+			return xvalidator.FieldError("Discount", xvalidator.ValidatorError{Reason: "out of range"})
+		}
+	}
+	if err := xvalidator.ValidateFields(x, "Note"); err != nil {
+		return err
+	}
+	if err := x.Address.Validate(); err != nil {
+		return xvalidator.Locate("Address", err)
+	}
+	if err := xvalidator.ValidateFields(x, "Billing"); err != nil {
+		return err
+	}
+	if err := xvalidator.ValidateFields(x, "Items"); err != nil {
+		return err
+	}
+	return xvalidator.CallHook(x)
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ccbhj/xvalidator"
+	"github.com/ccbhj/xvalidator/internal"
+	"github.com/pkg/errors"
+)
+
+const defaultOutput = "xvalidator_gen.go"
+
+// generate return the source of the Validate methods of the structs in names
+// of the package in dir, all the structs with tags are generated if names is
+// empty. The file named output is ignored when loading the package.
+func generate(dir, output string, names []string) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	}
+	structs, err := lookupStructs(pkg, names)
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{pkg: pkg, imports: map[string]bool{"github.com/ccbhj/xvalidator": true}}
+	for _, s := range structs {
+		g.generated = append(g.generated, s.name)
+	}
+	for _, s := range structs {
+		if err := g.genStruct(s); err != nil {
+			return nil, err
+		}
+	}
+	return g.source()
+}
+
+// loadPackage parses and type-checks the package in dir
+func loadPackage(dir, output string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(bp.Name, fset, files, nil)
+}
+
+// structType is a struct type to generate
+type structType struct {
+	name string
+	typ  *types.Struct
+	hook bool // whether it implements Validatable or ValidatableCtx
+}
+
+// lookupStructs return the structs named names in pkg, or all the structs with
+// tags
+func lookupStructs(pkg *types.Package, names []string) ([]structType, error) {
+	scope := pkg.Scope()
+	all := len(names) == 0
+	if all {
+		names = scope.Names()
+	}
+	var structs []structType
+	for _, name := range names {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			if !all {
+				return nil, errors.Errorf("%s is not a type", name)
+			}
+			continue
+		}
+		st, ok := obj.Type().Underlying().(*types.Struct)
+		if named, isNamed := obj.Type().(*types.Named); isNamed && named.TypeParams().Len() > 0 {
+			// the validation of generic structs is left to the reflective path
+			ok = false
+		}
+		if !ok || !hasTags(st) {
+			if !all {
+				return nil, errors.Errorf("%s is not a struct with xvldt tags", name)
+			}
+			continue
+		}
+		if sel, _, _ := types.LookupFieldOrMethod(types.NewPointer(obj.Type()), false, pkg, "Validate"); sel != nil {
+			return nil, errors.Errorf("%s already has a Validate field or method", name)
+		}
+		structs = append(structs, structType{name: name, typ: st, hook: hasHook(pkg, obj.Type())})
+	}
+	if len(structs) == 0 {
+		return nil, errors.New("no struct with xvldt tags found")
+	}
+	return structs, nil
+}
+
+// hasHook report whether typ has the methods of xvalidator.Validatable or
+// xvalidator.ValidatableCtx
+func hasHook(pkg *types.Package, typ types.Type) bool {
+	for _, name := range []string{"XValidateCtx", "XValidate"} {
+		if m, _, _ := types.LookupFieldOrMethod(types.NewPointer(typ), false, pkg, name); m != nil {
+			if _, ok := m.(*types.Func); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasTags(st *types.Struct) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		if _, has := reflect.StructTag(st.Tag(i)).Lookup(xvalidator.DefaultTagName); has {
+			return true
+		}
+	}
+	return false
+}
+
+// generator writes the Validate methods of a package
+type generator struct {
+	pkg       *types.Package
+	buf       bytes.Buffer
+	imports   map[string]bool
+	patterns  []string // patterns of regex() compiled in package variables
+	generated []string // names of the structs generated
+	fallback  bool     // whether any field is validated with ValidateFields
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) isGenerated(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() != g.pkg {
+		return false
+	}
+	for _, name := range g.generated {
+		if name == named.Obj().Name() {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) genStruct(s structType) error {
+	g.printf("// Validate validates the fields of %s like xvalidator.ValidateStruct.\n", s.name)
+	g.printf("func (x *%s) Validate() error {\n", s.name)
+	g.printf("if x == nil {\nreturn xvalidator.ErrInvalidStruct\n}\n")
+	if needsReflection(s.typ) {
+		// the promoted fields and the unexported fields are left to the
+		// reflective path
+		g.fallback = true
+		g.printf("return xvalidator.ValidateStruct(x)\n}\n\n")
+	} else {
+		for i := 0; i < s.typ.NumFields(); i++ {
+			f := s.typ.Field(i)
+			tag, has := reflect.StructTag(s.typ.Tag(i)).Lookup(xvalidator.DefaultTagName)
+			if !has || tag == "-" {
+				continue
+			}
+			if err := g.genField(s.name, f, tag); err != nil {
+				return err
+			}
+		}
+		if s.hook {
+			// the hook runs after all the fields pass like ValidateStruct
+			g.printf("return xvalidator.CallHook(x)\n}\n\n")
+		} else {
+			g.printf("return nil\n}\n\n")
+		}
+	}
+	return nil
+}
+
+// needsReflection report whether st has embedded fields, or unexported fields
+// with tags which ValidateFields cannot select
+func needsReflection(st *types.Struct) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		_, has := reflect.StructTag(st.Tag(i)).Lookup(xvalidator.DefaultTagName)
+		if f.Embedded() || (has && !f.Exported()) {
+			return true
+		}
+	}
+	return false
+}
+
+// genField writes the validation of a field, the builtin validators are
+// inlined and the others fall back to ValidateFields
+func (g *generator) genField(strct string, f *types.Var, tag string) error {
+	sections, err := internal.ParseSections(tag)
+	if err != nil {
+		return errors.WithMessagef(err, "invalid tag of %s.%s", strct, f.Name())
+	}
+	var calls []internal.Call
+	grouped := false
+	for _, sec := range sections {
+		secCalls, err := internal.ParseCalls(sec.Rules)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid tag of %s.%s", strct, f.Name())
+		}
+		grouped = grouped || sec.Group != ""
+		calls = append(calls, secCalls...)
+	}
+
+	var code bytes.Buffer
+	if !grouped && g.inline(&code, f, calls) {
+		g.buf.Write(code.Bytes())
+		return nil
+	}
+	g.fallback = true
+	g.printf("if err := xvalidator.ValidateFields(x, %q); err != nil {\nreturn err\n}\n", f.Name())
+	return nil
+}
+
+// inline writes the inlined calls into w, it return false if any call cannot
+// be inlined
+func (g *generator) inline(w *bytes.Buffer, f *types.Var, calls []internal.Call) bool {
+	name := f.Name()
+	field := "x." + name
+	if len(calls) == 1 && calls[0].Name == "strct" && strings.TrimSpace(calls[0].Args) == "" {
+		return g.inlineStruct(w, f)
+	}
+
+	basic, ok := f.Type().(*types.Basic)
+	if !ok {
+		return false
+	}
+	isString := basic.Kind() == types.String
+	isInt := basic.Info()&types.IsInteger != 0 && basic.Kind() != types.Uintptr
+	if !isString && !isInt {
+		return false
+	}
+	zero := "0"
+	if isString {
+		zero = `""`
+	}
+	fail := func(reason string) string {
+		return fmt.Sprintf("return xvalidator.FieldError(%q, xvalidator.ValidatorError{Reason: %q})\n", name, reason)
+	}
+
+	var (
+		body   bytes.Buffer
+		guards int
+		imps   []string
+		pats   []string
+	)
+	for _, call := range calls {
+		noArgs := strings.TrimSpace(call.Args) == ""
+		arg, err := internal.ParseArguments(call.Args)
+		if err != nil || len(arg.Vars) > 0 {
+			return false
+		}
+		switch {
+		case call.Name == "omitempty" && noArgs:
+			fmt.Fprintf(&body, "if %s != %s {\n", field, zero)
+			guards++
+		case call.Name == "required" && noArgs:
+			fmt.Fprintf(&body, "if %s == %s {\n%s}\n", field, zero, fail("required"))
+		case call.Name == "not_empty" && isString:
+			imps = append(imps, "strings")
+			fmt.Fprintf(&body, "if strings.TrimSpace(%s) == \"\" {\n%s}\n", field, fail("empty string"))
+		case call.Name == "len" && isString && len(arg.Ints) > 0:
+			fmt.Fprintf(&body, "if uint64(len(%s)) != %d {\n%s}\n", field, arg.Ints[0], fail("invalid string length"))
+		case call.Name == "srange" && isString:
+			if len(arg.Strs) == 0 {
+				// an empty srange() rejects everything
+				fmt.Fprintf(&body, "%s", fail("invalid value"))
+				continue
+			}
+			fmt.Fprintf(&body, "switch %s {\ncase %s:\ndefault:\n%s}\n", field, quoteAll(arg.Strs), fail("invalid value"))
+		case call.Name == "regex" && isString && len(arg.Strs) > 0:
+			if _, err := regexp.Compile(arg.Strs[0]); err != nil {
+				return false
+			}
+			imps = append(imps, "regexp")
+			pats = append(pats, arg.Strs[0])
+			fmt.Fprintf(&body, "if !xvldtPatterns[%d].MatchString(%s) {\n%s}\n",
+				len(g.patterns)+len(pats)-1, field, fail("string not match pattern"))
+		case call.Name == "max" && isInt && len(arg.Ints) > 0:
+			fmt.Fprintf(&body, "if uint64(%s) > %d {\n%s}\n", field, arg.Ints[0], fail("out of range"))
+		case call.Name == "min" && isInt && len(arg.Ints) > 0:
+			fmt.Fprintf(&body, "if uint64(%s) < %d {\n%s}\n", field, arg.Ints[0], fail("out of range"))
+		case call.Name == "irange" && isInt:
+			// duplicated cases do not compile
+			ints := make([]string, 0, len(arg.Ints))
+			seen := make(map[uint64]bool, len(arg.Ints))
+			for _, i := range arg.Ints {
+				if !seen[i] {
+					seen[i] = true
+					ints = append(ints, strconv.FormatUint(i, 10))
+				}
+			}
+			if len(ints) == 0 {
+				// an empty irange() rejects everything
+				fmt.Fprintf(&body, "%s", fail("invalid value"))
+				continue
+			}
+			fmt.Fprintf(&body, "switch uint64(%s) {\ncase %s:\ndefault:\n%s}\n", field, strings.Join(ints, ", "), fail("invalid value"))
+		default:
+			return false
+		}
+	}
+	body.WriteString(strings.Repeat("}\n", guards))
+
+	w.Write(body.Bytes())
+	for _, imp := range imps {
+		g.imports[imp] = true
+	}
+	g.patterns = append(g.patterns, pats...)
+	return true
+}
+
+// inlineStruct writes the call to the generated Validate of a nested struct,
+// pointers are left to the reflective path which stops at pointer cycles
+func (g *generator) inlineStruct(w *bytes.Buffer, f *types.Var) bool {
+	if !g.isGenerated(f.Type()) {
+		return false
+	}
+	fmt.Fprintf(w, "if err := x.%s.Validate(); err != nil {\nreturn xvalidator.Locate(%q, err)\n}\n", f.Name(), f.Name())
+	return true
+}
+
+// quoteAll return the distinct strs quoted as the cases of a switch
+func quoteAll(strs []string) string {
+	quoted := make([]string, 0, len(strs))
+	seen := make(map[string]bool, len(strs))
+	for _, s := range strs {
+		if !seen[s] {
+			seen[s] = true
+			quoted = append(quoted, strconv.Quote(s))
+		}
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// source return the formatted source of the generated file
+func (g *generator) source() ([]byte, error) {
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by xvalidator-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", g.pkg.Name())
+	// the standard packages go first like goimports does
+	var std, others []string
+	for imp := range g.imports {
+		if strings.Contains(strings.SplitN(imp, "/", 2)[0], ".") {
+			others = append(others, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+	fmt.Fprintf(&src, "import (\n")
+	for _, imp := range std {
+		fmt.Fprintf(&src, "%q\n", imp)
+	}
+	if len(std) > 0 {
+		fmt.Fprintf(&src, "\n")
+	}
+	for _, imp := range others {
+		fmt.Fprintf(&src, "%q\n", imp)
+	}
+	fmt.Fprintf(&src, ")\n\n")
+
+	if len(g.patterns) > 0 {
+		fmt.Fprintf(&src, "var xvldtPatterns = [...]*regexp.Regexp{\n")
+		for _, pat := range g.patterns {
+			fmt.Fprintf(&src, "regexp.MustCompile(%s),\n", strconv.Quote(pat))
+		}
+		fmt.Fprintf(&src, "}\n\n")
+	}
+	if g.fallback {
+		// ValidateFields and ValidateStruct need the structs registered
+		fmt.Fprintf(&src, "func init() {\n")
+		fmt.Fprintf(&src, "if err := xvalidator.RegisterStructs(")
+		for _, name := range g.generated {
+			fmt.Fprintf(&src, "%s{}, ", name)
+		}
+		fmt.Fprintf(&src, "); err != nil {\npanic(err)\n}\n}\n\n")
+	}
+	src.Write(g.buf.Bytes())
+	return format.Source(src.Bytes())
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := "example"
+	want, err := os.ReadFile(filepath.Join(dir, defaultOutput))
+	assert.Nil(t, err)
+	src, err := generate(dir, defaultOutput, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, string(want), string(src), "example/xvalidator_gen.go is out of date, run go generate")
+
+	src, err = generate(dir, defaultOutput, []string{"Address"})
+	assert.Nil(t, err)
+	assert.Contains(t, string(src), "func (x *Address) Validate() error")
+	assert.NotContains(t, string(src), "func (x *Order) Validate() error")
+
+	_, err = generate(dir, defaultOutput, []string{"Unknown"})
+	assert.NotNil(t, err)
+	// the generated file is not excluded, so Validate exists
+	_, err = generate(dir, "other.go", []string{"Address"})
+	assert.NotNil(t, err)
+}
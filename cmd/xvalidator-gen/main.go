@@ -0,0 +1,52 @@
+// Command xvalidator-gen generates reflection-free Validate methods for the
+// structs with 'xvldt' tags in a package, usually with go generate:
+//
+//	//go:generate go run github.com/ccbhj/xvalidator/cmd/xvalidator-gen -type Order,Item
+//
+// The builtin validators are inlined as plain Go, the fields with other rules
+// are validated with xvalidator.ValidateFields, so the generated methods
+// return the same errors as xvalidator.ValidateStruct.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		typeNames = flag.String("type", "", "comma-separated list of type names, all the structs with xvldt tags by default")
+		output    = flag.String("output", "", "output file name, <dir>/xvalidator_gen.go by default")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: xvalidator-gen [-type T1,T2] [-output file] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	out := *output
+	if out == "" {
+		out = filepath.Join(dir, defaultOutput)
+	}
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+
+	src, err := generate(dir, filepath.Base(out), types)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "xvalidator-gen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(out, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "xvalidator-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
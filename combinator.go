@@ -0,0 +1,129 @@
+package xvalidator
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ccbhj/xvalidator/internal"
+	"github.com/pkg/errors"
+)
+
+// names of the combinators, they take validator calls as arguments
+const (
+	orCombinatorName  string = "or"
+	notCombinatorName string = "not"
+	allCombinatorName string = "all"
+)
+
+// branch is an alternative of or(...)
+type branch struct {
+	name  string
+	rules []rule
+}
+
+// orRule passes if any of its branches passes
+// The error of every branch is reported in the Reason and kept in Err as a
+// ValidationErrors.
+type orRule struct {
+	branches []branch
+}
+
+func (o orRule) check(r *run, v reflect.Value) error {
+	var (
+		errs    ValidationErrors
+		reasons = make([]string, 0, len(o.branches))
+	)
+	for _, b := range o.branches {
+		err := r.probe(b.rules, v)
+		if err == nil {
+			return nil
+		}
+		if r.abort != nil {
+			return r.abort
+		}
+		for _, e := range validationErrors(err) {
+			reason := e.Reason
+			if len(e.Path) > 0 {
+				reason = e.Path.String() + ": " + reason
+			}
+			reasons = append(reasons, b.name+": "+reason)
+			errs = append(errs, e)
+		}
+	}
+	return ValidatorError{
+		Reason: "none of the alternatives passed: " + strings.Join(reasons, "; "),
+		Err:    errs,
+	}
+}
+
+// notRule passes if its rules fail
+type notRule struct {
+	desc  string
+	rules []rule
+}
+
+func (n notRule) check(r *run, v reflect.Value) error {
+	err := r.probe(n.rules, v)
+	if r.abort != nil {
+		return r.abort
+	}
+	if err == nil {
+		return ValidatorError{Reason: "must not pass " + n.desc}
+	}
+	return nil
+}
+
+// allRule passes if all its rules pass, it groups rules for or(...) and
+// not(...)
+type allRule struct {
+	rules []rule
+}
+
+func (a allRule) check(r *run, v reflect.Value) error {
+	return r.checkRules(a.rules, v)
+}
+
+// probe runs rules against v in fail-fast mode without counting the errors,
+// it is used by the rules whose result depends on whether other rules fail.
+func (r *run) probe(rules []rule, v reflect.Value) error {
+	all, n := r.all, r.n
+	r.all = false
+	defer func() { r.all, r.n = all, n }()
+	return r.checkRules(rules, v)
+}
+
+// compileCombinator compiles or(...), not(...) and all(...):
+//
+//	or(a(), b())    passes if a() or b() passes, use all() to group rules
+//	not(a(), b())   passes if a() or b() fails
+//	all(a(), b())   passes if a() and b() pass
+func (c *compiler) compileCombinator(ft fieldTag, call internal.Call, typ reflect.Type) (rule, error) {
+	calls, err := c.parseCalls(ft, call.Args, call.ArgsOffset)
+	if err != nil {
+		return nil, err
+	}
+	if len(calls) == 0 {
+		return nil, c.tagError(ft, call.Name, call.Offset,
+			errors.WithMessagef(ErrInvalidArgument, "%s() requires validators", call.Name))
+	}
+	if call.Name == orCombinatorName {
+		o := orRule{branches: make([]branch, 0, len(calls))}
+		for _, nested := range calls {
+			rules, err := c.compileCalls(ft, []internal.Call{nested}, typ)
+			if err != nil {
+				return nil, err
+			}
+			o.branches = append(o.branches, branch{name: nested.Name, rules: rules})
+		}
+		return o, nil
+	}
+
+	rules, err := c.compileCalls(ft, calls, typ)
+	if err != nil {
+		return nil, err
+	}
+	if call.Name == notCombinatorName {
+		return notRule{desc: strings.TrimSpace(call.Args), rules: rules}, nil
+	}
+	return allRule{rules: rules}, nil
+}
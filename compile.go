@@ -0,0 +1,520 @@
+package xvalidator
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ccbhj/xvalidator/internal"
+	"github.com/pkg/errors"
+)
+
+// CompileStruct parse the 'xvldt' tag in the fields of a struct pointer or
+// struct value and return its Validator.
+// A TagError will be returned if any tag cannot be compiled.
+func (e *Engine) CompileStruct(strct interface{}) (Validator, error) {
+	typ, err := structType(strct)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := e.compileStruct(typ, "")
+	if err != nil {
+		return nil, err
+	}
+	return cs.validator(e), nil
+}
+
+// structType return the struct type of a struct pointer or struct value
+func structType(strct interface{}) (reflect.Type, error) {
+	typ := reflect.TypeOf(strct)
+	if typ != nil {
+		typ = internal.TypeIndirect(typ)
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, errors.WithMessage(ErrInvalidValidatorArgument, "must be struct or struct pointer")
+	}
+	return typ, nil
+}
+
+// compiler compiles the tags of a group of struct types with a registry
+// snapshot
+type compiler struct {
+	e   *Engine
+	reg *registry
+	// pending holds the structs being compiled, a reference to any of them
+	// is resolved to the placeholder directly so that cycles in the type
+	// graph can be compiled.
+	pending map[reflect.Type]*compiledStruct
+	// onDemand holds the structs compiled by auto-compile
+	onDemand []*compiledStruct
+	// group selects the sections of tags to compile, only the sections
+	// without a group are compiled if it is empty
+	group string
+}
+
+// fieldTag is the tag of the field being compiled, it is used to locate a
+// TagError
+type fieldTag struct {
+	strct reflect.Type
+	field reflect.StructField
+	tag   string
+}
+
+func (c *compiler) tagError(ft fieldTag, name string, offset int, err error) error {
+	return TagError{
+		Struct:    ft.strct,
+		Field:     ft.field.Name,
+		Validator: name,
+		Tag:       ft.tag,
+		Offset:    offset,
+		Err:       err,
+	}
+}
+
+// compileStruct parse the 'xvldt' tag of all the fields in typ
+func (e *Engine) compileStruct(typ reflect.Type, group string) (*compiledStruct, error) {
+	css, err := e.compileStructs([]reflect.Type{typ}, group)
+	if err != nil {
+		return nil, err
+	}
+	return css[0], nil
+}
+
+// compileStructs compiles a group of struct types which may refer to each
+// other, with the rules of a validation group
+func (e *Engine) compileStructs(typs []reflect.Type, group string) ([]*compiledStruct, error) {
+	c := &compiler{e: e, reg: e.load(), pending: make(map[reflect.Type]*compiledStruct), group: group}
+	css := make([]*compiledStruct, 0, len(typs))
+	for _, typ := range typs {
+		cs := &compiledStruct{typ: typ, group: group}
+		c.pending[typ] = cs
+		css = append(css, cs)
+	}
+	for _, cs := range css {
+		if err := c.compileFields(cs); err != nil {
+			return nil, err
+		}
+	}
+	for _, cs := range c.onDemand {
+		e.cache.LoadOrStore(structKey{typ: cs.typ, group: group}, cs)
+	}
+	return css, nil
+}
+
+// compileFields compiles the rules of all the fields of cs.typ into cs,
+// including the fields promoted from embedded structs, and the Validatable
+// hook of cs.typ
+func (c *compiler) compileFields(cs *compiledStruct) error {
+	cs.hook = newHookRule(cs.typ)
+	for _, field := range visibleFields(cs.typ, c.e.rules) {
+		tag, has := c.e.rules.Rules(field.owner, field.StructField)
+		if !has || tag == skipTag {
+			continue
+		}
+		rules, label, err := c.compileRules(fieldTag{strct: cs.typ, field: field.StructField, tag: tag})
+		if err != nil {
+			return err
+		}
+		name := c.e.fieldName(field.StructField)
+		if label == "" {
+			label = name
+		}
+		cs.fields = append(cs.fields, compiledField{
+			index: field.Index,
+			field: field.Name,
+			name:  name,
+			label: label,
+			rules: rules,
+		})
+	}
+	return nil
+}
+
+// skipTag is the tag to ignore a field, or to stop walking into an embedded
+// struct
+const skipTag = "-"
+
+// visibleField is a field of a struct or a field promoted from an embedded
+// struct
+type visibleField struct {
+	reflect.StructField
+	owner reflect.Type // the struct declaring the field
+}
+
+// visibleFields return the fields of typ and the fields promoted from the
+// embedded structs of typ in declaration order, a field promoted has the
+// full index path in Index.
+// An embedded struct is walked if it has no rules in src, the promotion
+// follows the rules of Go: a field hides the fields with the same name in
+// deeper levels and the fields with the same name in the same level hide each
+// other.
+func visibleFields(typ reflect.Type, src RuleSource) []visibleField {
+	type candidate struct {
+		field visibleField
+		depth int
+	}
+	byName := make(map[string][]candidate)
+	var walk func(typ reflect.Type, index []int, depth int, walking map[reflect.Type]bool)
+	walk = func(typ reflect.Type, index []int, depth int, walking map[reflect.Type]bool) {
+		walking[typ] = true
+		defer delete(walking, typ)
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			f.Index = append(append(make([]int, 0, len(index)+1), index...), i)
+			byName[f.Name] = append(byName[f.Name], candidate{field: visibleField{StructField: f, owner: typ}, depth: depth})
+
+			_, tagged := src.Rules(typ, f)
+			embedded := internal.TypeIndirect(f.Type)
+			if f.Anonymous && !tagged && embedded.Kind() == reflect.Struct && !walking[embedded] {
+				walk(embedded, f.Index, depth+1, walking)
+			}
+		}
+	}
+	walk(typ, nil, 0, make(map[reflect.Type]bool))
+
+	fields := make([]visibleField, 0, len(byName))
+	for _, cands := range byName {
+		min := cands[0]
+		hidden := false
+		for _, c := range cands[1:] {
+			if c.depth < min.depth {
+				min, hidden = c, false
+			} else if c.depth == min.depth {
+				hidden = true
+			}
+		}
+		if !hidden {
+			fields = append(fields, min.field)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].Index, fields[j].Index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// structFor return the compiled struct of typ in the group of c, typ will be
+// compiled if it is not registered and auto-compile is on. The struct of a
+// group is compiled on demand if typ is registered.
+// The struct returned may still be compiling.
+func (c *compiler) structFor(typ reflect.Type) (*compiledStruct, error) {
+	if cs, in := c.pending[typ]; in {
+		return cs, nil
+	}
+	cs, registered := c.reg.structs[typ]
+	if registered && c.group == "" {
+		return cs, nil
+	}
+	if cs, in := c.e.cache.Load(structKey{typ: typ, group: c.group}); in {
+		return cs.(*compiledStruct), nil
+	}
+	if !registered && !c.e.autoCompile {
+		return nil, errors.WithMessage(ErrStructNotRegister, typ.String())
+	}
+	cs = &compiledStruct{typ: typ, group: c.group}
+	c.pending[typ] = cs
+	c.onDemand = append(c.onDemand, cs)
+	return cs, c.compileFields(cs)
+}
+
+// compileRules compiles all the validator calls in the tag of a field and
+// return its label, the sections of a tag without a group and the sections of
+// c.group are compiled in order.
+// The sections of other groups are compiled but dropped when compiling
+// without a group, so that a bad tag is reported at registration.
+func (c *compiler) compileRules(ft fieldTag) ([]rule, string, error) {
+	sections, err := internal.ParseSections(ft.tag)
+	if err != nil {
+		return nil, "", c.tagError(ft, "", syntaxOffset(err, 0), err)
+	}
+	typ := internal.TypeIndirect(ft.field.Type)
+	var calls []internal.Call
+	for _, sec := range sections {
+		secCalls, err := c.parseCalls(ft, sec.Rules, sec.Offset)
+		if err != nil {
+			return nil, "", err
+		}
+		if sec.Group == "" || sec.Group == c.group {
+			calls = append(calls, secCalls...)
+		} else if c.group == "" {
+			if _, err := c.compileCalls(ft, secCalls, typ); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	calls, label, err := c.takeLabel(ft, calls)
+	if err != nil {
+		return nil, "", err
+	}
+	rules, err := c.compileCalls(ft, calls, typ)
+	return rules, label, err
+}
+
+// parseCalls parse the calls in s, which starts at base in the tag
+func (c *compiler) parseCalls(ft fieldTag, s string, base int) ([]internal.Call, error) {
+	calls, err := internal.ParseCalls(s)
+	if err != nil {
+		return nil, c.tagError(ft, "", syntaxOffset(err, base), err)
+	}
+	for i := range calls {
+		calls[i].Offset += base
+		calls[i].ArgsOffset += base
+	}
+	return calls, nil
+}
+
+// compileCalls compiles calls against values of typ
+func (c *compiler) compileCalls(ft fieldTag, calls []internal.Call, typ reflect.Type) ([]rule, error) {
+	rules := make([]rule, 0, len(calls))
+	for i, call := range calls {
+		var (
+			r   rule
+			err error
+		)
+		switch call.Name {
+		case omitEmptyModifierName:
+			if err := c.noArgs(ft, call); err != nil {
+				return nil, err
+			}
+			// omitempty() guards all the rules after it
+			rest, err := c.compileCalls(ft, calls[i+1:], typ)
+			if err != nil {
+				return nil, err
+			}
+			return append(rules, omitEmptyRule{rules: rest}), nil
+		case requiredValidatorName:
+			r, err = requiredRule{}, c.noArgs(ft, call)
+		case eachModifierName:
+			r, err = c.compileEach(ft, call, typ)
+		case keysModifierName, valuesModifierName:
+			r, err = c.compileMap(ft, call, typ)
+		case eqFieldValidatorName, neFieldValidatorName, gtFieldValidatorName,
+			gteFieldValidatorName, ltFieldValidatorName, lteFieldValidatorName:
+			r, err = c.compileCrossField(ft, call, typ)
+		case requiredIfValidatorName, requiredUnlessValidatorName, requiredWithValidatorName,
+			requiredWithoutValidatorName, whenModifierName:
+			r, err = c.compileConditional(ft, call, typ)
+		case orCombinatorName, notCombinatorName, allCombinatorName:
+			r, err = c.compileCombinator(ft, call, typ)
+		case labelModifierName:
+			err = c.tagError(ft, call.Name, call.Offset,
+				errors.WithMessage(ErrInvalidArgument, "label() can only be used at the top level of a field"))
+		default:
+			r, err = c.compileCall(ft, call, typ)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// compileEach compiles each(...) whose arguments are the validators applied to
+// every element of a slice or an array, elements that are structs are
+// validated with their compiled struct as well.
+func (c *compiler) compileEach(ft fieldTag, call internal.Call, typ reflect.Type) (rule, error) {
+	if k := typ.Kind(); k != reflect.Slice && k != reflect.Array {
+		return nil, c.tagError(ft, call.Name, call.Offset,
+			errors.WithMessage(ErrInvalidArgument, "each() requires a slice or an array"))
+	}
+	rules, err := c.compileElem(ft, call, typ.Elem())
+	if err != nil {
+		return nil, err
+	}
+	return eachRule{rules: rules}, nil
+}
+
+// compileMap compiles keys(...) or values(...) whose arguments are the
+// validators applied to every key or every value of a map, values that are
+// structs are validated with their compiled struct as well.
+func (c *compiler) compileMap(ft fieldTag, call internal.Call, typ reflect.Type) (rule, error) {
+	if typ.Kind() != reflect.Map {
+		return nil, c.tagError(ft, call.Name, call.Offset,
+			errors.WithMessagef(ErrInvalidArgument, "%s() requires a map", call.Name))
+	}
+	elem := typ.Elem()
+	if call.Name == keysModifierName {
+		elem = typ.Key()
+	}
+	rules, err := c.compileElem(ft, call, elem)
+	if err != nil {
+		return nil, err
+	}
+	return mapRule{rules: rules, keys: call.Name == keysModifierName}, nil
+}
+
+// compileElem compiles the arguments of a modifier against its element type
+// elem, the compiled struct is added if elem is a struct to validate, see
+// validatesElem.
+func (c *compiler) compileElem(ft fieldTag, call internal.Call, elem reflect.Type) ([]rule, error) {
+	calls, err := c.parseCalls(ft, call.Args, call.ArgsOffset)
+	if err != nil {
+		return nil, err
+	}
+	elem = internal.TypeIndirect(elem)
+	rules, err := c.compileCalls(ft, calls, elem)
+	if err != nil {
+		return nil, err
+	}
+	if elem.Kind() == reflect.Struct && !hasCall(calls, structValidatorName) && c.validatesElem(elem) {
+		nested, err := c.structFor(elem)
+		if err != nil {
+			return nil, c.tagError(ft, call.Name, call.Offset, err)
+		}
+		rules = append([]rule{structRule{cs: nested}}, rules...)
+	}
+	return rules, nil
+}
+
+// validatesElem report whether the struct elements of a list or a map are
+// validated with their compiled struct, which is the case if the struct is
+// registered, can be auto-compiled or has rules, so that the lists of
+// untagged structs like time.Time need no registration.
+func (c *compiler) validatesElem(elem reflect.Type) bool {
+	if _, in := c.pending[elem]; in {
+		return true
+	}
+	if _, registered := c.reg.structs[elem]; registered || c.e.autoCompile {
+		return true
+	}
+	if newHookRule(elem) != nil {
+		return true
+	}
+	for _, field := range visibleFields(elem, c.e.rules) {
+		if tag, has := c.e.rules.Rules(field.owner, field.StructField); has && tag != skipTag {
+			return true
+		}
+	}
+	return false
+}
+
+// inStruct checks that the rules being compiled belong to a struct field, it
+// is required by the validators that refer to the other fields
+func (c *compiler) inStruct(ft fieldTag, call internal.Call) error {
+	if ft.strct == nil {
+		return c.tagError(ft, call.Name, call.Offset,
+			errors.WithMessagef(ErrInvalidArgument, "%s() requires a struct field", call.Name))
+	}
+	return nil
+}
+
+// noArgs checks that call takes no arguments
+func (c *compiler) noArgs(ft fieldTag, call internal.Call) error {
+	if strings.TrimSpace(call.Args) != "" {
+		return c.tagError(ft, call.Name, call.ArgsOffset,
+			errors.WithMessagef(ErrInvalidArgument, "%s() takes no arguments", call.Name))
+	}
+	return nil
+}
+
+func hasCall(calls []internal.Call, name string) bool {
+	for _, call := range calls {
+		if call.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// compileCall compiles a validator call against values of typ
+func (c *compiler) compileCall(ft fieldTag, call internal.Call, typ reflect.Type) (rule, error) {
+	factory, in := c.reg.validators[call.Name]
+	if !in {
+		return nil, c.tagError(ft, call.Name, call.Offset, ErrUnknownValidator)
+	}
+	arg, err := internal.ParseArguments(call.Args)
+	if err != nil {
+		return nil, c.tagError(ft, call.Name, syntaxOffset(err, call.ArgsOffset), err)
+	}
+
+	if err := c.resolveConsts(ft, call, arg, call.ArgsOffset); err != nil {
+		return nil, err
+	}
+
+	if call.Name == structValidatorName {
+		return c.compileStructCall(ft, call, typ)
+	}
+
+	vld, err := newValidator(factory, ValidatorArgs{
+		Strs: arg.Strs,
+		Ints: arg.Ints,
+		Typ:  typ,
+
+		engine: c.e,
+	})
+	if err != nil {
+		return nil, c.tagError(ft, call.Name, call.Offset, err)
+	}
+	return funcRule{fn: vld}, nil
+}
+
+// resolveConsts replace the variables in arg with the registered values, the
+// arguments starts at base in the tag
+func (c *compiler) resolveConsts(ft fieldTag, call internal.Call, arg *internal.ArgsInfos, base int) error {
+	for i, v := range arg.Vars {
+		if ic, in := c.reg.constInts[v]; in {
+			arg.Ints = append(arg.Ints, ic)
+			continue
+		}
+		sc, in := c.reg.constStrs[v]
+		if !in {
+			return c.tagError(ft, call.Name, base+arg.VarOffsets[i],
+				errors.WithMessage(ErrUnknownConst, v))
+		}
+		arg.Strs = append(arg.Strs, sc)
+	}
+	return nil
+}
+
+// compileStructCall compiles strct() against a struct type, or a slice or
+// array of structs whose elements will be validated one by one
+func (c *compiler) compileStructCall(ft fieldTag, call internal.Call, typ reflect.Type) (rule, error) {
+	elem := typ
+	if k := typ.Kind(); k == reflect.Slice || k == reflect.Array {
+		elem = internal.TypeIndirect(typ.Elem())
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, c.tagError(ft, call.Name, call.Offset,
+			errors.WithMessage(ErrInvalidArgument, "strct() requires a struct or a list of structs"))
+	}
+	nested, err := c.structFor(elem)
+	if err != nil {
+		return nil, c.tagError(ft, call.Name, call.Offset, err)
+	}
+	return structRule{cs: nested}, nil
+}
+
+// newValidator calls factory and turns a panic into an error, so that the
+// factories registered with RegisterValidator cannot crash the compilation
+func newValidator(factory ValidatorCtxFactory, args ValidatorArgs) (vld ValidatorCtx, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			if e, ok := p.(error); ok {
+				err = e
+			} else {
+				err = errors.Errorf("%v", p)
+			}
+		}
+	}()
+	vld, err = factory(args)
+	if err == nil && vld == nil {
+		vld = Validator(dummyValidator).withCtx()
+	}
+	return vld, err
+}
+
+// syntaxOffset return the offset of a syntax error shifted by base
+func syntaxOffset(err error, base int) int {
+	var se *internal.SyntaxError
+	if errors.As(err, &se) {
+		return base + se.Offset
+	}
+	return base
+}
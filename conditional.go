@@ -0,0 +1,200 @@
+package xvalidator
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/ccbhj/xvalidator/internal"
+	"github.com/pkg/errors"
+)
+
+// names of the conditional validators, they depend on other fields of the
+// same struct
+const (
+	requiredIfValidatorName      string = "required_if"
+	requiredUnlessValidatorName  string = "required_unless"
+	requiredWithValidatorName    string = "required_with"
+	requiredWithoutValidatorName string = "required_without"
+	whenModifierName             string = "when"
+)
+
+// condition is checked against the struct being validated
+type condition interface {
+	holds(strct reflect.Value) bool
+}
+
+// valueCondition holds if the field is equal to any of the values, or none of
+// them if negated
+type valueCondition struct {
+	field   []int
+	values  map[string]struct{}
+	negated bool
+}
+
+func (c valueCondition) holds(strct reflect.Value) bool {
+	in := false
+	if field, ok := fieldByIndex(strct, c.field); ok {
+		if s, ok := valueString(indirect(field)); ok {
+			_, in = c.values[s]
+		}
+	}
+	return in != c.negated
+}
+
+// presenceCondition holds if any of the fields is not empty, or is empty if
+// negated
+type presenceCondition struct {
+	fields  [][]int
+	negated bool
+}
+
+func (c presenceCondition) holds(strct reflect.Value) bool {
+	for _, index := range c.fields {
+		field, ok := fieldByIndex(strct, index)
+		if (ok && !isEmpty(field)) != c.negated {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionalRule runs its rules only if the condition holds
+type conditionalRule struct {
+	cond  condition
+	rules []rule
+}
+
+func (c conditionalRule) check(r *run, v reflect.Value) error {
+	if !c.cond.holds(r.strct) {
+		return nil
+	}
+	return r.checkRules(c.rules, v)
+}
+
+// compileConditional compiles a conditional validator:
+//
+//	required_if(Field, 'a', 'b')   required if Field is 'a' or 'b'
+//	required_unless(Field, 'a')    required unless Field is 'a'
+//	required_with(A, B)            required if A or B is not empty
+//	required_without(A, B)         required if A or B is empty
+//	when(Field, 'a', validators)   run validators if Field is 'a'
+//
+// The fields are resolved like the cross-field validators.
+func (c *compiler) compileConditional(ft fieldTag, call internal.Call, typ reflect.Type) (rule, error) {
+	if err := c.inStruct(ft, call); err != nil {
+		return nil, err
+	}
+	args, err := internal.SplitArgs(call.Args)
+	if err != nil {
+		return nil, c.tagError(ft, call.Name, syntaxOffset(err, call.ArgsOffset), err)
+	}
+	if len(args) == 0 || args[0].Text == "" {
+		return nil, c.tagError(ft, call.Name, call.ArgsOffset,
+			errors.WithMessagef(ErrInvalidArgument, "%s() requires a field name", call.Name))
+	}
+
+	switch call.Name {
+	case requiredWithValidatorName, requiredWithoutValidatorName:
+		cond := presenceCondition{negated: call.Name == requiredWithoutValidatorName}
+		for _, arg := range args {
+			index, _, err := c.conditionField(ft, call, arg)
+			if err != nil {
+				return nil, err
+			}
+			cond.fields = append(cond.fields, index)
+		}
+		return conditionalRule{cond: cond, rules: []rule{requiredRule{}}}, nil
+	case whenModifierName:
+		if len(args) < 3 {
+			return nil, c.tagError(ft, call.Name, call.Offset,
+				errors.WithMessage(ErrInvalidArgument, "when() requires a field, a value and validators"))
+		}
+		cond, err := c.valueCondition(ft, call, args[:2], false)
+		if err != nil {
+			return nil, err
+		}
+		start := args[2].Offset
+		calls, err := c.parseCalls(ft, call.Args[start:], call.ArgsOffset+start)
+		if err != nil {
+			return nil, err
+		}
+		rules, err := c.compileCalls(ft, calls, typ)
+		if err != nil {
+			return nil, err
+		}
+		return conditionalRule{cond: cond, rules: rules}, nil
+	}
+
+	if len(args) < 2 {
+		return nil, c.tagError(ft, call.Name, call.Offset,
+			errors.WithMessagef(ErrInvalidArgument, "%s() requires a field and values", call.Name))
+	}
+	cond, err := c.valueCondition(ft, call, args, call.Name == requiredUnlessValidatorName)
+	if err != nil {
+		return nil, err
+	}
+	return conditionalRule{cond: cond, rules: []rule{requiredRule{}}}, nil
+}
+
+// valueCondition compiles a field and the values it is compared with
+func (c *compiler) valueCondition(ft fieldTag, call internal.Call, args []internal.Arg, negated bool) (condition, error) {
+	index, typ, err := c.conditionField(ft, call, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := valueString(reflect.Zero(typ)); !ok {
+		return nil, c.tagError(ft, call.Name, call.ArgsOffset+args[0].Offset,
+			errors.WithMessagef(ErrInvalidArgument, "cannot compare %s with a value", typ))
+	}
+
+	start := args[1].Offset
+	end := args[len(args)-1].Offset + len(args[len(args)-1].Text)
+	arg, err := internal.ParseArguments(call.Args[start:end])
+	if err != nil {
+		return nil, c.tagError(ft, call.Name, syntaxOffset(err, call.ArgsOffset+start), err)
+	}
+	if err := c.resolveConsts(ft, call, arg, call.ArgsOffset+start); err != nil {
+		return nil, err
+	}
+	cond := valueCondition{field: index, values: make(map[string]struct{}), negated: negated}
+	for _, s := range arg.Strs {
+		cond.values[s] = struct{}{}
+	}
+	for _, i := range arg.Ints {
+		cond.values[strconv.FormatUint(i, 10)] = struct{}{}
+	}
+	return cond, nil
+}
+
+// conditionField resolves the field named by arg
+func (c *compiler) conditionField(ft fieldTag, call internal.Call, arg internal.Arg) ([]int, reflect.Type, error) {
+	path, err := fieldPathArg(arg.Text)
+	if err == nil {
+		var (
+			index []int
+			typ   reflect.Type
+		)
+		if index, typ, err = resolveFieldPath(ft.strct, path); err == nil {
+			return index, typ, nil
+		}
+	}
+	return nil, nil, c.tagError(ft, call.Name, call.ArgsOffset+arg.Offset, err)
+}
+
+// valueString formats a string, a number or a bool to be compared with the
+// arguments of a condition, it return false for other kinds or a nil value.
+func valueString(v reflect.Value) (string, bool) {
+	switch k := v.Kind(); {
+	case k == reflect.String:
+		return v.String(), true
+	case k == reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case isInt(k):
+		return strconv.FormatInt(v.Int(), 10), true
+	case isUint(k):
+		return strconv.FormatUint(v.Uint(), 10), true
+	case k == reflect.Float32 || k == reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
+	}
+	return "", false
+}
@@ -0,0 +1,246 @@
+package xvalidator
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ccbhj/xvalidator/internal"
+	"github.com/pkg/errors"
+)
+
+// names of the cross-field validators, they compare a field with another
+// field of the same struct
+const (
+	eqFieldValidatorName  string = "eqfield"
+	neFieldValidatorName  string = "nefield"
+	gtFieldValidatorName  string = "gtfield"
+	gteFieldValidatorName string = "gtefield"
+	ltFieldValidatorName  string = "ltfield"
+	lteFieldValidatorName string = "ltefield"
+)
+
+// crossFieldOps holds the description of each cross-field validator and
+// whether the result of a comparison passes it
+var crossFieldOps = map[string]struct {
+	desc    string
+	ordered bool
+	pass    func(cmp int) bool
+}{
+	eqFieldValidatorName:  {"be equal to", false, func(cmp int) bool { return cmp == 0 }},
+	neFieldValidatorName:  {"not be equal to", false, func(cmp int) bool { return cmp != 0 }},
+	gtFieldValidatorName:  {"be greater than", true, func(cmp int) bool { return cmp > 0 }},
+	gteFieldValidatorName: {"be greater than or equal to", true, func(cmp int) bool { return cmp >= 0 }},
+	ltFieldValidatorName:  {"be less than", true, func(cmp int) bool { return cmp < 0 }},
+	lteFieldValidatorName: {"be less than or equal to", true, func(cmp int) bool { return cmp <= 0 }},
+}
+
+var fieldPathPat = regexp.MustCompile(`^` + nameRegex + `(\.` + nameRegex + `)*$`)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// crossFieldRule compares a value with another field of the struct being
+// validated
+type crossFieldRule struct {
+	other   []int // index path of the other field
+	reason  string
+	ordered bool
+	pass    func(cmp int) bool
+}
+
+func (c crossFieldRule) check(r *run, v reflect.Value) error {
+	other, ok := fieldByIndex(r.strct, c.other)
+	if ok {
+		other = indirect(other)
+	}
+	v = indirect(v)
+
+	var cmp int
+	if !v.IsValid() || !other.IsValid() {
+		if c.ordered {
+			return ValidatorError{Reason: "nil value"}
+		}
+		// two nils are equal
+		if v.IsValid() || other.IsValid() {
+			cmp = 1
+		}
+	} else if cmp, ok = compareValues(v, other); !ok {
+		// values of different types are not equal, but two values of a type
+		// that cannot be compared, like slices held by interfaces, are an error
+		if c.ordered || v.Type() == other.Type() {
+			return ValidatorError{Reason: "not comparable"}
+		}
+		cmp = 1
+	}
+	if !c.pass(cmp) {
+		return ValidatorError{Reason: c.reason}
+	}
+	return nil
+}
+
+// compileCrossField compiles a cross-field validator like eqfield(Password),
+// the other field is resolved against the struct being compiled and can be
+// a dotted path into nested structs.
+func (c *compiler) compileCrossField(ft fieldTag, call internal.Call, typ reflect.Type) (rule, error) {
+	if err := c.inStruct(ft, call); err != nil {
+		return nil, err
+	}
+	path, err := fieldPathArg(call.Args)
+	if err != nil {
+		return nil, c.tagError(ft, call.Name, call.ArgsOffset, err)
+	}
+	index, otherTyp, err := resolveFieldPath(ft.strct, path)
+	if err != nil {
+		return nil, c.tagError(ft, call.Name, call.ArgsOffset, err)
+	}
+	op := crossFieldOps[call.Name]
+	if _, ok := compareValues(reflect.Zero(typ), reflect.Zero(otherTyp)); !ok ||
+		(op.ordered && !isOrdered(typ)) {
+		return nil, c.tagError(ft, call.Name, call.Offset,
+			errors.WithMessagef(ErrInvalidArgument, "cannot compare %s with %s", typ, otherTyp))
+	}
+	return crossFieldRule{
+		other:   index,
+		reason:  "must " + op.desc + " " + path,
+		ordered: op.ordered,
+		pass:    op.pass,
+	}, nil
+}
+
+// fieldPathArg return the field path in the arguments of a cross-field
+// validator, it can be quoted or not.
+func fieldPathArg(args string) (string, error) {
+	path := strings.TrimSpace(args)
+	if strings.HasPrefix(path, "'") {
+		arg, err := internal.ParseArguments(path)
+		if err != nil {
+			return "", err
+		}
+		if len(arg.Strs) != 1 || len(arg.Ints)+len(arg.Vars) > 0 {
+			return "", errors.WithMessage(ErrInvalidArgument, "required one field name")
+		}
+		path = arg.Strs[0]
+	}
+	if !fieldPathPat.MatchString(path) {
+		return "", errors.WithMessagef(ErrInvalidArgument, "invalid field name %q", path)
+	}
+	return path, nil
+}
+
+// resolveFieldPath return the index path and the type of a dotted field path
+// in strct
+func resolveFieldPath(strct reflect.Type, path string) ([]int, reflect.Type, error) {
+	var index []int
+	typ := strct
+	for _, name := range strings.Split(path, ".") {
+		if typ.Kind() != reflect.Struct {
+			return nil, nil, errors.WithMessage(ErrUnknownField, path)
+		}
+		f, ok := typ.FieldByName(name)
+		if !ok || f.PkgPath != "" {
+			return nil, nil, errors.WithMessage(ErrUnknownField, path)
+		}
+		index = append(index, f.Index...)
+		typ = internal.TypeIndirect(f.Type)
+	}
+	return index, typ, nil
+}
+
+func isOrdered(typ reflect.Type) bool {
+	if typ == timeType {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return isInt(typ.Kind()) || isUint(typ.Kind())
+}
+
+// compareValues compares a with b, it return false if they cannot be
+// compared. Numbers of different kinds can be compared with each other, values
+// that are not ordered are compared for equality only.
+func compareValues(a, b reflect.Value) (int, bool) {
+	ak, bk := a.Kind(), b.Kind()
+	switch {
+	case a.Type() == timeType && b.Type() == timeType:
+		ta, tb := a.Interface().(time.Time), b.Interface().(time.Time)
+		return boolsToCmp(ta.Before(tb), ta.After(tb)), true
+	case ak == reflect.String && bk == reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+	case isInt(ak) && isInt(bk):
+		x, y := a.Int(), b.Int()
+		return boolsToCmp(x < y, x > y), true
+	case isUint(ak) && isUint(bk):
+		x, y := a.Uint(), b.Uint()
+		return boolsToCmp(x < y, x > y), true
+	case isNumber(ak) && isNumber(bk):
+		x, y := toFloat(a), toFloat(b)
+		return boolsToCmp(x < y, x > y), true
+	case a.Type() == b.Type() && a.Type().Comparable():
+		if !canEqual(a) || !canEqual(b) {
+			return 0, false
+		}
+		if a.Interface() == b.Interface() {
+			return 0, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+// canEqual report whether v can be compared with == without a panic, the
+// dynamic values of the interfaces in v must be comparable too
+func canEqual(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface:
+		return v.IsNil() || canEqual(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !canEqual(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !canEqual(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return v.Type().Comparable()
+}
+
+func boolsToCmp(less, greater bool) int {
+	if less {
+		return -1
+	} else if greater {
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case isInt(v.Kind()):
+		return float64(v.Int())
+	case isUint(v.Kind()):
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isNumber(k reflect.Kind) bool {
+	return isInt(k) || isUint(k) || k == reflect.Float32 || k == reflect.Float64
+}
@@ -0,0 +1,361 @@
+package xvalidator
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ccbhj/xvalidator/internal"
+	"github.com/pkg/errors"
+)
+
+// Engine owns its own registries of validators, constants and structs, so
+// that different packages in one binary can use different validators without
+// interfering with each other.
+// An Engine is safe for concurrent use. Registrations are copy-on-write, so
+// validating never takes a lock.
+type Engine struct {
+	mu     sync.Mutex // serializes writers
+	sealed bool
+	reg    atomic.Value // *registry
+
+	// cache holds the structs compiled automatically, see WithAutoCompile, and
+	// the structs compiled for validation groups
+	cache sync.Map // structKey -> *compiledStruct
+	// vars holds the rules compiled for ValidateVar, at most maxVars of them
+	vars  sync.Map // varKey -> []rule
+	nvars int32
+
+	allErrors   bool
+	maxErrors   int
+	autoCompile bool
+	maxDepth    int
+	rules       RuleSource
+	names       NameResolver
+}
+
+// registry is an immutable snapshot of everything registered in an Engine.
+// Writers must modify a clone and publish it with Engine.update.
+type registry struct {
+	structs    map[reflect.Type]*compiledStruct
+	constInts  map[string]uint64
+	constStrs  map[string]string
+	validators map[string]ValidatorCtxFactory
+}
+
+func (r *registry) clone() *registry {
+	c := &registry{
+		structs:    make(map[reflect.Type]*compiledStruct, len(r.structs)+1),
+		constInts:  make(map[string]uint64, len(r.constInts)+1),
+		constStrs:  make(map[string]string, len(r.constStrs)+1),
+		validators: make(map[string]ValidatorCtxFactory, len(r.validators)+1),
+	}
+	for k, v := range r.structs {
+		c.structs[k] = v
+	}
+	for k, v := range r.constInts {
+		c.constInts[k] = v
+	}
+	for k, v := range r.constStrs {
+		c.constStrs[k] = v
+	}
+	for k, v := range r.validators {
+		c.validators[k] = v
+	}
+	return c
+}
+
+// Option configures an Engine created by New
+type Option func(*Engine)
+
+// WithAllErrors makes the Engine report every failing rule of every field
+// instead of stopping at the first one. The error returned will be a
+// ValidationErrors.
+func WithAllErrors() Option {
+	return func(e *Engine) {
+		e.allErrors = true
+	}
+}
+
+// WithMaxErrors limits the number of errors collected in all-errors mode,
+// n <= 0 means no limit.
+func WithMaxErrors(n int) Option {
+	return func(e *Engine) {
+		e.maxErrors = n
+	}
+}
+
+// WithAutoCompile makes the Engine compile a struct the first time it is
+// validated instead of returning ErrStructNotRegister, nested structs will be
+// compiled on demand too, so the registration order does not matter anymore.
+func WithAutoCompile() Option {
+	return func(e *Engine) {
+		e.autoCompile = true
+	}
+}
+
+// DefaultMaxDepth is the default number of nested structs that can be
+// validated in a single validation
+const DefaultMaxDepth = 64
+
+// WithMaxDepth limits the number of nested structs that can be validated in a
+// single validation, ErrMaxDepth will be returned if the limit is exceeded.
+func WithMaxDepth(n int) Option {
+	return func(e *Engine) {
+		e.maxDepth = n
+	}
+}
+
+// WithTagName makes the Engine read the rules from the struct tag name
+// instead of DefaultTagName
+func WithTagName(name string) Option {
+	return func(e *Engine) {
+		e.rules = TagRules(name)
+	}
+}
+
+// WithRuleSource makes the Engine read the rules of struct fields from srcs
+// instead of the 'xvldt' tag, the first RuleSource that has the rules of a
+// field wins. Add TagRules(DefaultTagName) to srcs to keep reading the tags.
+func WithRuleSource(srcs ...RuleSource) Option {
+	return func(e *Engine) {
+		e.rules = ruleSources(srcs)
+	}
+}
+
+var defaultEngine *Engine
+
+func init() {
+	defaultEngine = New()
+}
+
+// New return a new Engine with all the builtin validators registered
+func New(opts ...Option) *Engine {
+	e := &Engine{maxDepth: DefaultMaxDepth, rules: TagRules(DefaultTagName)}
+	e.reg.Store(&registry{
+		structs:    make(map[reflect.Type]*compiledStruct),
+		constInts:  make(map[string]uint64),
+		constStrs:  make(map[string]string),
+		validators: make(map[string]ValidatorCtxFactory),
+	})
+	e.registerBuiltins()
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Default return the Engine used by the package level functions
+func Default() *Engine {
+	return defaultEngine
+}
+
+func (e *Engine) registerBuiltins() {
+	_ = e.RegisterValidatorFactory(maxValidatorName, newMaxValidator)
+	_ = e.RegisterValidatorFactory(minValidatorName, newMinValidator)
+	_ = e.RegisterValidator(iRangeValidatorName, IntRangeValidator)
+	_ = e.RegisterValidatorFactory(stringRangeValidatorName, newStringRangeValidator)
+	_ = e.RegisterValidator(structValidatorName, StructValidator)
+	_ = e.RegisterValidatorFactory(regexValidatorName, newRegexMatchValidator)
+	_ = e.RegisterValidatorFactory(notEmptyValidatorName, newNotEmptyValidator)
+	_ = e.RegisterValidatorFactory(lenValidatorName, newLenValidator)
+}
+
+// load return the current registry snapshot
+func (e *Engine) load() *registry {
+	return e.reg.Load().(*registry)
+}
+
+// update applies fn to a copy of the current registry and publish the copy
+// fn must not keep the registry after returning
+func (e *Engine) update(fn func(r *registry)) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.sealed {
+		return ErrEngineSealed
+	}
+	r := e.load().clone()
+	fn(r)
+	e.reg.Store(r)
+	return nil
+}
+
+// Seal freezes the registries of e, all the registrations after Seal is called
+// will fail with ErrEngineSealed.
+func (e *Engine) Seal() {
+	e.mu.Lock()
+	e.sealed = true
+	e.mu.Unlock()
+}
+
+// Sealed report whether e has been sealed
+func (e *Engine) Sealed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.sealed
+}
+
+// RegisterConstStr registers a string constant
+// name must start with letter and consist of letters and numbers
+func (e *Engine) RegisterConstStr(name, val string) error {
+	if !constNamePat.MatchString(name) {
+		return errors.WithMessage(ErrInvalidName, name)
+	}
+	return e.update(func(r *registry) {
+		r.constStrs[name] = val
+	})
+}
+
+// RegisterConstInt registers an integer constant
+// name must start with letter and consist of letters and numbers
+func (e *Engine) RegisterConstInt(name string, val uint64) error {
+	if !constNamePat.MatchString(name) {
+		return errors.WithMessage(ErrInvalidName, name)
+	}
+	return e.update(func(r *registry) {
+		r.constInts[name] = val
+	})
+}
+
+// RegisterValidator registers a custom validator
+// name must start with letter and consist of letters and numbers
+// A panic in factory is reported as a TagError when a struct is compiled.
+func (e *Engine) RegisterValidator(name string, factory func(args ValidatorArgs) Validator) error {
+	return e.RegisterValidatorFactory(name, func(args ValidatorArgs) (Validator, error) {
+		return factory(args), nil
+	})
+}
+
+// RegisterValidatorFactory registers a custom validator whose factory reports
+// invalid arguments with an error
+// name must start with letter and consist of letters and numbers
+func (e *Engine) RegisterValidatorFactory(name string, factory ValidatorFactory) error {
+	return e.RegisterValidatorCtx(name, factory.withCtx())
+}
+
+// RegisterValidatorCtx registers a custom validator that can access the
+// context passed to ValidateStructCtx
+// name must start with letter and consist of letters and numbers
+func (e *Engine) RegisterValidatorCtx(name string, factory ValidatorCtxFactory) error {
+	if !namePat.MatchString(string(name)) || reservedNames[name] {
+		return errors.WithMessage(ErrInvalidName, name)
+	}
+	return e.update(func(r *registry) {
+		r.validators[name] = factory
+	})
+}
+
+// RegisterStruct generate a validator for a struct pointer or struct value
+// Registering a struct twice is a no-op.
+// A TagError will be returned if any tag of the struct is invalid.
+func (e *Engine) RegisterStruct(strct interface{}) error {
+	return e.RegisterStructs(strct)
+}
+
+// RegisterStructs registers a group of struct pointers or struct values which
+// may refer to each other with strct(), it is required if the structs form a
+// cycle and auto-compile is off.
+func (e *Engine) RegisterStructs(strcts ...interface{}) error {
+	reg := e.load()
+	typs := make([]reflect.Type, 0, len(strcts))
+	for _, strct := range strcts {
+		typ, err := structType(strct)
+		if err != nil {
+			return err
+		}
+		if _, in := reg.structs[typ]; !in {
+			typs = append(typs, typ)
+		}
+	}
+	if len(typs) == 0 {
+		return nil
+	}
+	if e.Sealed() {
+		return ErrEngineSealed
+	}
+	// compile outside the lock, the validator only depends on the snapshot
+	css, err := e.compileStructs(typs, "")
+	if err != nil {
+		return err
+	}
+	return e.update(func(r *registry) {
+		for _, cs := range css {
+			if _, in := r.structs[cs.typ]; !in {
+				r.structs[cs.typ] = cs
+			}
+		}
+	})
+}
+
+// ValidateStruct validates a struct pointer of struct value
+// The struct must be registed before ValidateStruct is called
+func (e *Engine) ValidateStruct(strct interface{}) error {
+	return e.validateStruct(e.newRun(context.Background(), false), strct, "")
+}
+
+// ValidateStructCtx validates a struct pointer of struct value like
+// ValidateStruct, ctx is passed to the validators registered with
+// RegisterValidatorCtx.
+// The validation stops with ctx.Err() once ctx is done, it is checked before
+// each field.
+func (e *Engine) ValidateStructCtx(ctx context.Context, strct interface{}) error {
+	return e.validateStruct(e.newRun(ctx, false), strct, "")
+}
+
+// ValidateStructAll validates a struct pointer of struct value like
+// ValidateStruct, but keep going after a rule fails and return all the errors
+// as a ValidationErrors in field order.
+func (e *Engine) ValidateStructAll(strct interface{}) error {
+	return e.validateStruct(e.newRun(context.Background(), true), strct, "")
+}
+
+// ValidateStructGroup validates a struct pointer of struct value like
+// ValidateStruct, with the rules of a validation group and the rules without
+// a group. The rules of a group are prefixed with its name in tags:
+//
+//	ID string `xvldt:"create: len(0); update: len(36)"`
+//
+// The struct is compiled for each group on demand and cached, it must be
+// registered first unless auto-compile is on.
+func (e *Engine) ValidateStructGroup(strct interface{}, group string) error {
+	if group != "" && !internal.IsName(group) {
+		return errors.WithMessagef(ErrInvalidName, "invalid group name %q", group)
+	}
+	return e.validateStruct(e.newRun(context.Background(), false), strct, group)
+}
+
+func (e *Engine) validateStruct(r *run, strct interface{}, group string) error {
+	typ, err := structType(strct)
+	if err != nil {
+		return err
+	}
+	cs, err := e.structFor(e.load(), typ, group)
+	if err != nil {
+		return err
+	}
+	return cs.validate(r, strct)
+}
+
+// structFor return the compiled struct of typ in group from reg or the cache,
+// typ will be compiled and cached if it is registered for a group or
+// auto-compile is on.
+func (e *Engine) structFor(reg *registry, typ reflect.Type, group string) (*compiledStruct, error) {
+	cs, registered := reg.structs[typ]
+	if registered && group == "" {
+		return cs, nil
+	}
+	key := structKey{typ: typ, group: group}
+	if cs, in := e.cache.Load(key); in {
+		return cs.(*compiledStruct), nil
+	}
+	if !registered && !e.autoCompile {
+		return nil, ErrStructNotRegister
+	}
+	cs, err := e.compileStruct(typ, group)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := e.cache.LoadOrStore(key, cs)
+	return actual.(*compiledStruct), nil
+}
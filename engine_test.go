@@ -0,0 +1,207 @@
+package xvalidator
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineIsolation(t *testing.T) {
+	type S struct {
+		I int `xvldt:"max(LIMIT), even()"`
+	}
+	even := func(ValidatorArgs) Validator {
+		return func(arg interface{}) error {
+			if arg.(int)%2 != 0 {
+				return ValidatorError{Reason: "odd number"}
+			}
+			return nil
+		}
+	}
+
+	e1, e2 := New(), New()
+	e1.RegisterConstInt("LIMIT", 10)
+	e1.RegisterValidator("even", even)
+	e2.RegisterConstInt("LIMIT", 100)
+	e2.RegisterValidator("even", func(ValidatorArgs) Validator { return dummyValidator })
+	e1.RegisterStruct(S{})
+	e2.RegisterStruct(&S{})
+
+	assert.Nil(t, e1.ValidateStruct(S{I: 8}))
+	assert.NotNil(t, e1.ValidateStruct(S{I: 7}))
+	assert.NotNil(t, e1.ValidateStruct(S{I: 50}))
+	assert.Nil(t, e2.ValidateStruct(S{I: 7}))
+	assert.Nil(t, e2.ValidateStruct(&S{I: 50}))
+
+	// neither engine leaks into the default one
+	assert.Equal(t, ErrStructNotRegister, ValidateStruct(S{}))
+	assert.Panics(t, func() { NewStructValidator(S{}) })
+}
+
+func TestEngineConcurrentUse(t *testing.T) {
+	type A struct {
+		I int    `xvldt:"max(LIMIT)"`
+		S string `xvldt:"not_empty()"`
+	}
+	type B struct {
+		A A `xvldt:"strct()"`
+	}
+	e := New()
+	assert.Nil(t, e.RegisterConstInt("LIMIT", 10))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// lazy registration from many goroutines at once
+			assert.Nil(t, e.RegisterStruct(A{}))
+			assert.Nil(t, e.RegisterConstStr(fmt.Sprintf("C%d", i), "c"))
+			for j := 0; j < 100; j++ {
+				assert.Nil(t, e.ValidateStruct(A{I: j % 10, S: "s"}))
+				assert.NotNil(t, e.ValidateStruct(&A{I: 11, S: "s"}))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Nil(t, e.RegisterStruct(B{}))
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.Nil(t, e.ValidateStruct(B{A: A{I: 1, S: "s"}}))
+				assert.NotNil(t, e.ValidateStruct(B{A: A{I: 1}}))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestEngineSeal(t *testing.T) {
+	type S struct {
+		I int `xvldt:"max(10)"`
+	}
+	type T struct {
+		I int `xvldt:"min(10)"`
+	}
+	e := New()
+	assert.Nil(t, e.RegisterStruct(S{}))
+	assert.False(t, e.Sealed())
+	e.Seal()
+	assert.True(t, e.Sealed())
+
+	assert.Equal(t, ErrEngineSealed, e.RegisterStruct(T{}))
+	assert.Equal(t, ErrEngineSealed, e.RegisterConstInt("LIMIT", 1))
+	assert.Equal(t, ErrEngineSealed, e.RegisterConstStr("NAME", "x"))
+	assert.Equal(t, ErrEngineSealed, e.RegisterValidator("noop", func(ValidatorArgs) Validator { return dummyValidator }))
+	// already registered struct can still be registered and validated
+	assert.Nil(t, e.RegisterStruct(&S{}))
+	assert.Nil(t, e.ValidateStruct(S{I: 1}))
+	assert.NotNil(t, e.ValidateStruct(S{I: 11}))
+	assert.Equal(t, ErrStructNotRegister, e.ValidateStruct(T{}))
+}
+
+func TestEngineInvalidName(t *testing.T) {
+	e := New()
+	assert.True(t, errors.Is(e.RegisterConstInt("123", 1), ErrInvalidName))
+	assert.True(t, errors.Is(e.RegisterValidator("", nil), ErrInvalidName))
+	assert.Panics(t, func() { RegisterConstStr("", "") })
+}
+
+func TestEngineAutoCompile(t *testing.T) {
+	type A struct {
+		I int `xvldt:"max(10)"`
+	}
+	type B struct {
+		A *A `xvldt:"strct()"`
+	}
+	type C struct {
+		B B `xvldt:"strct()"`
+	}
+	type Bad struct {
+		I int `xvldt:"len(1)"`
+	}
+
+	e := New()
+	assert.Equal(t, ErrStructNotRegister, e.ValidateStruct(C{}))
+	assert.True(t, errors.Is(e.RegisterStruct(C{}), ErrStructNotRegister))
+
+	e = New(WithAutoCompile())
+	// outer struct first, nested structs are compiled on demand
+	assert.Nil(t, e.RegisterStruct(C{}))
+	assert.Nil(t, e.ValidateStruct(C{B: B{A: &A{I: 1}}}))
+	assert.NotNil(t, e.ValidateStruct(&C{B: B{A: &A{I: 11}}}))
+	assert.NotNil(t, e.ValidateStruct(B{A: &A{I: 11}}))
+	assert.Nil(t, e.ValidateStruct(A{I: 10}))
+
+	var te TagError
+	assert.True(t, errors.As(e.ValidateStruct(Bad{}), &te))
+	assert.True(t, errors.Is(e.ValidateStruct(1), ErrInvalidValidatorArgument))
+
+	// the cache is safe for concurrent use
+	type D struct {
+		S string `xvldt:"not_empty()"`
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, e.ValidateStruct(D{S: "x"}))
+			assert.NotNil(t, e.ValidateStruct(D{}))
+		}()
+	}
+	wg.Wait()
+}
+
+type sourceBase struct {
+	ID string
+}
+
+type sourceUser struct {
+	sourceBase
+	Name  string `validate:"not_empty()" xvldt:"len(100)"`
+	Email string
+	Age   int `validate:"-"`
+}
+
+func TestEngineRuleSource(t *testing.T) {
+	u := sourceUser{}
+
+	e := New(WithTagName("validate"), WithAllErrors())
+	assert.Nil(t, e.RegisterStruct(u))
+	assert.Equal(t, []string{"Name: empty string"}, describe(e.ValidateStruct(&u)))
+
+	table := RuleTable{
+		reflect.TypeOf(sourceBase{}): {"ID": "len(36)"},
+		reflect.TypeOf(sourceUser{}): {"Email": "not_empty()", "Age": "min(18)"},
+	}
+	e = New(WithRuleSource(table), WithAllErrors(), WithAutoCompile())
+	assert.Equal(t, []string{
+		"ID: invalid string length",
+		"Email: empty string",
+		"Age: out of range",
+	}, describe(e.ValidateStruct(u)))
+
+	e = New(WithRuleSource(table, TagRules("validate")), WithAllErrors(), WithAutoCompile())
+	assert.Equal(t, []string{
+		"ID: invalid string length",
+		"Name: empty string",
+		"Email: empty string",
+		"Age: out of range",
+	}, describe(e.ValidateStruct(u)))
+
+	e = New(WithRuleSource(RuleTable{reflect.TypeOf(sourceUser{}): {"Email": "mx(1)"}}))
+	err := e.RegisterStruct(u)
+	var te TagError
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, "Email", te.Field)
+	assert.Equal(t, "mx(1)", te.Tag)
+}
@@ -2,6 +2,8 @@ package xvalidator
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/ccbhj/xvalidator/internal"
 	"github.com/pkg/errors"
@@ -13,13 +15,117 @@ var ErrUnknownValidator = errors.New("unknown validator")
 var ErrStructNotRegister = errors.New("struct not registered")
 var ErrUnknownConst = errors.New("unknown const")
 var ErrInvalidArgument = errors.New("invalid argument for valiator")
+var ErrInvalidName = errors.New("invalid name")
+var ErrEngineSealed = errors.New("engine is sealed")
+var ErrMaxDepth = errors.New("max depth of nested structs exceeded")
+var ErrUnknownField = errors.New("unknown field")
+var ErrMissingKey = errors.New("missing key")
+var ErrUnknownKey = errors.New("unknown key")
+var ErrTypeMismatch = errors.New("type mismatch")
 var ErrInvalidValidatorSyntax = internal.ErrInvalidValidatorSyntax
 
 type ValidatorError struct {
 	Reason    string
 	FieldName string
+	// Path locates the failing value from the root of the validation
+	Path Path
+	// Err is the original error if the validator did not return a
+	// ValidatorError
+	Err error
 }
 
 func (e ValidatorError) Error() string {
-	return fmt.Sprintf("validate fail for field %s: %s", e.FieldName, e.Reason)
+	name := e.FieldName
+	if len(e.Path) > 0 {
+		name = e.Path.String()
+	}
+	if name == "" {
+		return fmt.Sprintf("validate fail: %s", e.Reason)
+	}
+	if len(e.Path) > 0 && e.FieldName != "" && e.FieldName != e.Path.lastField() {
+		// FieldName is a label
+		return fmt.Sprintf("validate fail for field %s (%s): %s", e.FieldName, name, e.Reason)
+	}
+	return fmt.Sprintf("validate fail for field %s: %s", name, e.Reason)
+}
+
+func (e ValidatorError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors holds all the errors of a validation in field order, it is
+// returned in all-errors mode.
+type ValidationErrors []ValidatorError
+
+func (es ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// orNil return nil if es is empty, so that a nil error can be returned
+func (es ValidationErrors) orNil() error {
+	if len(es) == 0 {
+		return nil
+	}
+	return es
+}
+
+// Unwrap return all the errors so that errors.Is and errors.As can inspect
+// each of them
+func (es ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(es))
+	for _, e := range es {
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// Is report whether any of the errors matches target, errors.Is only walks
+// Unwrap() []error since go1.20
+func (es ValidationErrors) Is(target error) bool {
+	for _, e := range es {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches target like errors.As
+func (es ValidationErrors) As(target interface{}) bool {
+	for _, e := range es {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// TagError is returned when the tag of a struct field cannot be compiled
+// Struct is nil for the rules of ValidateVar or a Schema, Field is the key
+// path of a Schema.
+type TagError struct {
+	Struct    reflect.Type
+	Field     string
+	Validator string // name of the validator, empty if it cannot be parsed
+	Tag       string
+	Offset    int // byte offset in Tag where the problem is
+	Err       error
+}
+
+func (e TagError) Error() string {
+	if e.Struct == nil && e.Field != "" {
+		return fmt.Sprintf("invalid rules %q of key %s at offset %d: %s", e.Tag, e.Field, e.Offset, e.Err)
+	} else if e.Struct == nil {
+		return fmt.Sprintf("invalid rules %q at offset %d: %s", e.Tag, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("invalid tag %q of field %s.%s at offset %d: %s",
+		e.Tag, e.Struct, e.Field, e.Offset, e.Err)
+}
+
+func (e TagError) Unwrap() error {
+	return e.Err
 }
@@ -1,10 +1,8 @@
 package xvalidator
 
 import (
-	"reflect"
+	"context"
 	"regexp"
-
-	"github.com/ccbhj/xvalidator/internal"
 )
 
 var namePat = regexp.MustCompile(nameRegex)
@@ -21,61 +19,174 @@ const (
 	regexValidatorName       string = "regex"
 )
 
-func init() {
-	RegisterValidator(maxValidatorName, MaxValidator)
-	RegisterValidator(minValidatorName, MinValidator)
-	RegisterValidator(iRangeValidatorName, IntRangeValidator)
-	RegisterValidator(stringRangeValidatorName, StringRangeValidator)
-	RegisterValidator(structValidatorName, StructValidator)
-	RegisterValidator(regexValidatorName, RegexMatchValiator)
-	RegisterValidator(notEmptyValidatorName, NotEmptyValidator)
-	RegisterValidator(lenValidatorName, LenValidator)
+// names handled by the compiler, they cannot be registered
+const (
+	requiredValidatorName string = "required"
+	eachModifierName      string = "each"
+	keysModifierName      string = "keys"
+	valuesModifierName    string = "values"
+	omitEmptyModifierName string = "omitempty"
+)
+
+var reservedNames = map[string]bool{
+	requiredValidatorName: true,
+	eachModifierName:      true,
+	keysModifierName:      true,
+	valuesModifierName:    true,
+	omitEmptyModifierName: true,
+	eqFieldValidatorName:  true,
+	neFieldValidatorName:  true,
+	gtFieldValidatorName:  true,
+	gteFieldValidatorName: true,
+	ltFieldValidatorName:  true,
+	lteFieldValidatorName: true,
+
+	requiredIfValidatorName:      true,
+	requiredUnlessValidatorName:  true,
+	requiredWithValidatorName:    true,
+	requiredWithoutValidatorName: true,
+	whenModifierName:             true,
+
+	orCombinatorName:  true,
+	notCombinatorName: true,
+	allCombinatorName: true,
+	labelModifierName: true,
 }
 
-// RegisterConstStr registers a string constant
+// RegisterConstStr registers a string constant in the default Engine
 // name must start with letter and consist of letters and numbers
 func RegisterConstStr(name, val string) {
-	if !constNamePat.MatchString(name) {
-		panic("invalid constant name")
-	}
-	registeredConstStr[name] = val
+	mustRegister(defaultEngine.RegisterConstStr(name, val))
 }
 
-// RegisterConstStr registers an integer constant
+// RegisterConstInt registers an integer constant in the default Engine
 // name must start with letter and consist of letters and numbers
 func RegisterConstInt(name string, val uint64) {
-	if !constNamePat.MatchString(name) {
-		panic("invalid constant name")
-	}
-	registeredConstInt[name] = val
+	mustRegister(defaultEngine.RegisterConstInt(name, val))
 }
 
-// RegisterValidator registers a custom validator
+// RegisterValidator registers a custom validator in the default Engine
 // name must start with letter and consist of letters and numbers
 func RegisterValidator(name string, factory func(args ValidatorArgs) Validator) {
-	if !namePat.MatchString(string(name)) {
-		panic("invalid constant name")
-	}
-	registeredValidator[name] = factory
+	mustRegister(defaultEngine.RegisterValidator(name, factory))
+}
+
+// RegisterValidatorFactory registers a custom validator whose factory reports
+// invalid arguments with an error in the default Engine
+// name must start with letter and consist of letters and numbers
+func RegisterValidatorFactory(name string, factory ValidatorFactory) {
+	mustRegister(defaultEngine.RegisterValidatorFactory(name, factory))
+}
+
+// RegisterValidatorCtx registers a custom validator that can access the
+// context of the validation in the default Engine
+// name must start with letter and consist of letters and numbers
+func RegisterValidatorCtx(name string, factory ValidatorCtxFactory) {
+	mustRegister(defaultEngine.RegisterValidatorCtx(name, factory))
 }
 
 // RegisterStruct generate a validator for a struct pointer or struct value
+// with the default Engine
+// RegisterStruct panics if any tag is invalid, use TryRegisterStruct to get an
+// error instead.
 func RegisterStruct(strct interface{}) {
-	typ := internal.TypeIndirect(reflect.TypeOf(strct))
-	_, in := registeredStruct[typ]
-	if in {
-		return
+	mustRegister(defaultEngine.RegisterStruct(strct))
+}
+
+// RegisterStructs registers a group of structs which may refer to each other
+// with the default Engine, see Engine.RegisterStructs
+func RegisterStructs(strcts ...interface{}) error {
+	return defaultEngine.RegisterStructs(strcts...)
+}
+
+// TryRegisterStruct is like RegisterStruct but return a TagError instead of
+// panicking if any tag of the struct is invalid
+func TryRegisterStruct(strct interface{}) error {
+	return defaultEngine.RegisterStruct(strct)
+}
+
+// CompileStruct parse the 'xvldt' tag of a struct with the default Engine, see
+// Engine.CompileStruct
+func CompileStruct(strct interface{}) (Validator, error) {
+	return defaultEngine.CompileStruct(strct)
+}
+
+// Seal freezes the default Engine, see Engine.Seal
+func Seal() {
+	defaultEngine.Seal()
+}
+
+func mustRegister(err error) {
+	if err != nil {
+		panic(err)
 	}
-	registeredStruct[typ] = NewStructValidator(strct)
 }
 
-// ValidateStruct validates a struct pointer of struct value
+// ValidateStruct validates a struct pointer of struct value with the default
+// Engine
 // The struct must be registed before ValidateStruct is called
 func ValidateStruct(strct interface{}) error {
-	typ := internal.TypeIndirect(reflect.TypeOf(strct))
-	vld, in := registeredStruct[typ]
-	if !in {
-		return ErrStructNotRegister
-	}
-	return vld(strct)
+	return defaultEngine.ValidateStruct(strct)
+}
+
+// ValidateStructCtx validates a struct pointer of struct value with the default
+// Engine, see Engine.ValidateStructCtx
+func ValidateStructCtx(ctx context.Context, strct interface{}) error {
+	return defaultEngine.ValidateStructCtx(ctx, strct)
+}
+
+// ValidateStructAll validates a struct pointer of struct value with the default
+// Engine and return all the errors, see Engine.ValidateStructAll
+func ValidateStructAll(strct interface{}) error {
+	return defaultEngine.ValidateStructAll(strct)
+}
+
+// ValidateStructGroup validates a struct pointer of struct value with the
+// rules of a validation group in the default Engine, see
+// Engine.ValidateStructGroup
+func ValidateStructGroup(strct interface{}, group string) error {
+	return defaultEngine.ValidateStructGroup(strct, group)
+}
+
+// ValidateFields validates only the fields at paths of a struct pointer or
+// struct value with the default Engine, see Engine.ValidateFields
+func ValidateFields(strct interface{}, paths ...string) error {
+	return defaultEngine.ValidateFields(strct, paths...)
+}
+
+// ValidateExcept validates all the fields of a struct pointer or struct value
+// except the fields at paths with the default Engine, see
+// Engine.ValidateExcept
+func ValidateExcept(strct interface{}, paths ...string) error {
+	return defaultEngine.ValidateExcept(strct, paths...)
+}
+
+// ValidateVar validates a single value with rules in the default Engine, see
+// Engine.ValidateVar
+func ValidateVar(value interface{}, rules string) error {
+	return defaultEngine.ValidateVar(value, rules)
+}
+
+// ValidateNamedVar validates a single value with rules in the default Engine,
+// see Engine.ValidateNamedVar
+func ValidateNamedVar(name string, value interface{}, rules string) error {
+	return defaultEngine.ValidateNamedVar(name, value, rules)
+}
+
+// CompileSchema compiles rules into a Schema with the default Engine, see
+// Engine.CompileSchema
+func CompileSchema(rules map[string]string, opts ...SchemaOption) (*Schema, error) {
+	return defaultEngine.CompileSchema(rules, opts...)
+}
+
+// ValidateMap validates a document with rules in the default Engine, see
+// Engine.ValidateMap
+func ValidateMap(doc interface{}, rules map[string]string, opts ...SchemaOption) error {
+	return defaultEngine.ValidateMap(doc, rules, opts...)
+}
+
+// NewStructValidator parse the 'xvldt' tag in struct's fields and return a new
+// Validator using the validators and constants of the default Engine.
+func NewStructValidator(args interface{}) Validator {
+	return defaultEngine.NewStructValidator(args)
 }
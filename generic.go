@@ -0,0 +1,130 @@
+package xvalidator
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// TypedValidator validates values of the struct type T, see Compile
+type TypedValidator[T any] struct {
+	e  *Engine
+	cs *compiledStruct
+}
+
+// Compile compiles the rules of the struct type T, or the struct T points
+// to, with the default Engine.
+// A TagError will be returned if any rule cannot be compiled.
+func Compile[T any]() (TypedValidator[T], error) {
+	return CompileWith[T](defaultEngine)
+}
+
+// CompileWith compiles the rules of the struct type T, or the struct T points
+// to, with e
+func CompileWith[T any](e *Engine) (TypedValidator[T], error) {
+	typ, err := typeOf[T]()
+	if err != nil {
+		return TypedValidator[T]{}, err
+	}
+	cs, err := e.compileStruct(typ, "")
+	if err != nil {
+		return TypedValidator[T]{}, err
+	}
+	return TypedValidator[T]{e: e, cs: cs}, nil
+}
+
+// Validate validates v like Engine.ValidateStruct
+func (tv TypedValidator[T]) Validate(v T) error {
+	return tv.validate(context.Background(), false, v)
+}
+
+// ValidateCtx validates v like Engine.ValidateStructCtx
+func (tv TypedValidator[T]) ValidateCtx(ctx context.Context, v T) error {
+	return tv.validate(ctx, false, v)
+}
+
+// ValidateAll validates v like Engine.ValidateStructAll
+func (tv TypedValidator[T]) ValidateAll(v T) error {
+	return tv.validate(context.Background(), true, v)
+}
+
+// validate return ErrStructNotRegister if tv is not returned by Compile
+func (tv TypedValidator[T]) validate(ctx context.Context, all bool, v T) error {
+	if tv.cs == nil {
+		return ErrStructNotRegister
+	}
+	return tv.cs.validate(tv.e.newRun(ctx, all), v)
+}
+
+// Validate validates a struct or a struct pointer with the default Engine
+// like ValidateStruct, T is compiled the first time if auto-compile is on.
+func Validate[T any](v T) error {
+	typ, err := typeOf[T]()
+	if err != nil {
+		return err
+	}
+	cs, err := defaultEngine.structFor(defaultEngine.load(), typ, "")
+	if err != nil {
+		return err
+	}
+	return cs.validate(defaultEngine.newRun(context.Background(), false), v)
+}
+
+// typeOf return the struct type of T, or the struct type T points to
+func typeOf[T any]() (reflect.Type, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.WithMessagef(ErrInvalidValidatorArgument, "%s is not a struct or struct pointer", typ)
+	}
+	return typ, nil
+}
+
+// Func return a ValidatorFactory of a validator of T, the type of a field is
+// checked when its struct is compiled, so that a mismatch is a TagError
+// instead of an error at runtime. Fields of a named type of T, like
+// `type ID string` for Func[string], are converted to T.
+//
+//	e.RegisterValidatorFactory("slug", xvalidator.Func(func(s string) error {...}))
+func Func[T any](fn func(T) error) ValidatorFactory {
+	return func(args ValidatorArgs) (Validator, error) {
+		convert, err := converterTo[T](args.Typ)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}) error {
+			return fn(convert(v))
+		}, nil
+	}
+}
+
+// FuncCtx is like Func but fn receives the context of the validation
+func FuncCtx[T any](fn func(context.Context, T) error) ValidatorCtxFactory {
+	return func(args ValidatorArgs) (ValidatorCtx, error) {
+		convert, err := converterTo[T](args.Typ)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, v interface{}) error {
+			return fn(ctx, convert(v))
+		}, nil
+	}
+}
+
+// converterTo return a function that converts a value of typ to T, or an
+// error if typ is neither T nor convertible to T with the same kind
+func converterTo[T any](typ reflect.Type) (func(interface{}) T, error) {
+	target := reflect.TypeOf((*T)(nil)).Elem()
+	switch {
+	case typ == target:
+		return func(v interface{}) T { return v.(T) }, nil
+	case typ != nil && typ.Kind() == target.Kind() && typ.ConvertibleTo(target):
+		return func(v interface{}) T {
+			return reflect.ValueOf(v).Convert(target).Interface().(T)
+		}, nil
+	}
+	return nil, errors.WithMessagef(ErrInvalidArgument, "validator of %s cannot validate %s", target, typ)
+}
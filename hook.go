@@ -0,0 +1,137 @@
+package xvalidator
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Validatable is implemented by the structs that have rules which cannot be
+// written as tags. XValidate is called after all the fields of the struct pass,
+// or in all-errors mode, after all the fields are validated.
+// The error returned is merged into the result of the validation, use
+// FieldError to report it at a field.
+// The method is not named Validate, so that the common
+// `func (t *T) Validate() error { return xvalidator.ValidateStruct(t) }`
+// is not called back by the validation it starts.
+type Validatable interface {
+	XValidate() error
+}
+
+// ValidatableCtx is like Validatable but receives the context of the
+// validation, it takes precedence over Validatable.
+type ValidatableCtx interface {
+	XValidateCtx(ctx context.Context) error
+}
+
+var (
+	validatableType    = reflect.TypeOf((*Validatable)(nil)).Elem()
+	validatableCtxType = reflect.TypeOf((*ValidatableCtx)(nil)).Elem()
+)
+
+// FieldError marks err as the error of the field at path, so that an error
+// returned by Validatable is reported at the field rather than the struct.
+// path is in dotted form like `Items[3].SKU`, it is relative to the struct.
+func FieldError(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	p, ok := parsePath(path)
+	if !ok {
+		p = Path{fieldSegment(path)}
+	}
+	if es, ok := err.(ValidationErrors); ok {
+		located := make(ValidationErrors, 0, len(es))
+		for _, e := range es {
+			located = append(located, FieldError(path, e).(ValidatorError))
+		}
+		return located
+	}
+	var ve ValidatorError
+	if !errors.As(err, &ve) {
+		ve = ValidatorError{Reason: err.Error(), Err: err}
+	}
+	for i := len(p) - 1; i >= 0; i-- {
+		ve = locate(ve, p[i]).(ValidatorError)
+	}
+	return ve
+}
+
+// Locate prepends path to the paths of the ValidatorErrors in err like a
+// nested validation does, other errors are returned as they are. It is used by
+// the code generated by xvalidator-gen.
+func Locate(path string, err error) error {
+	p, ok := parsePath(path)
+	if !ok {
+		p = Path{fieldSegment(path)}
+	}
+	for i := len(p) - 1; i >= 0 && err != nil; i-- {
+		err = locate(err, p[i])
+	}
+	return err
+}
+
+// CallHook calls XValidateCtx with a background context, or XValidate, of a
+// struct pointer like ValidateStruct does after all the fields pass. It is
+// used by the code generated by xvalidator-gen.
+func CallHook(strct interface{}) error {
+	v := reflect.ValueOf(strct)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return ErrInvalidStruct
+	}
+	return hookRule{}.check(defaultEngine.newRun(context.Background(), false), v)
+}
+
+// hookRule calls XValidate or XValidateCtx of a struct, the struct is copied if
+// the method has a pointer receiver but the struct is not addressable.
+type hookRule struct {
+	ptr bool // whether the method has a pointer receiver
+}
+
+// newHookRule return the hookRule of typ, or nil if typ implements neither
+// Validatable nor ValidatableCtx
+func newHookRule(typ reflect.Type) rule {
+	// XValidateCtx takes precedence whatever the receivers of the methods are
+	switch {
+	case typ.Implements(validatableCtxType):
+		return hookRule{}
+	case reflect.PtrTo(typ).Implements(validatableCtxType):
+		return hookRule{ptr: true}
+	case typ.Implements(validatableType):
+		return hookRule{}
+	case reflect.PtrTo(typ).Implements(validatableType):
+		return hookRule{ptr: true}
+	}
+	return nil
+}
+
+func (h hookRule) check(r *run, v reflect.Value) error {
+	if h.ptr {
+		if v.CanAddr() {
+			v = v.Addr()
+		} else {
+			p := reflect.New(v.Type())
+			p.Elem().Set(v)
+			v = p
+		}
+	}
+	var err error
+	switch x := v.Interface().(type) {
+	case ValidatableCtx:
+		err = x.XValidateCtx(r.ctx)
+	case Validatable:
+		err = x.XValidate()
+	}
+	if err == nil {
+		return nil
+	}
+	if es, ok := err.(ValidationErrors); ok {
+		return es.orNil()
+	}
+	var ve ValidatorError
+	if !errors.As(err, &ve) {
+		ve = ValidatorError{Reason: err.Error(), Err: err}
+	}
+	return ve
+}
@@ -0,0 +1,233 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError reports where a tag cannot be parsed
+type SyntaxError struct {
+	Offset int // byte offset in the parsed string
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d: %s", ErrInvalidValidatorSyntax, e.Offset, e.Msg)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return ErrInvalidValidatorSyntax
+}
+
+func syntaxError(offset int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Offset: offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Call is a validator call in a tag like "max(10)"
+type Call struct {
+	Name       string
+	Args       string // raw arguments between the parentheses
+	Offset     int    // offset of Name
+	ArgsOffset int    // offset of Args
+}
+
+// ParseCalls parse all the validator calls in s, calls can be seperated with
+// ',' or spaces.
+// Parentheses inside the arguments must be balanced unless they are quoted,
+// so that calls can be nested.
+// example:
+//
+//	"max(10), regex('(a|b)')" => [{max 10} {regex '(a|b)'}]
+func ParseCalls(s string) ([]Call, error) {
+	var calls []Call
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c == sepRune || isSpace(c) {
+			i++
+			continue
+		}
+		if !isLetter(c) {
+			return nil, syntaxError(i, "unexpected %q, expect a validator name", c)
+		}
+		start := i
+		for i < len(s) && isNameChar(s[i]) {
+			i++
+		}
+		name := s[start:i]
+		if i >= len(s) || s[i] != '(' {
+			return nil, syntaxError(i, "expect '(' after %s", name)
+		}
+		end, err := closingParen(s, i)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, Call{
+			Name:       name,
+			Args:       s[i+1 : end],
+			Offset:     start,
+			ArgsOffset: i + 1,
+		})
+		i = end + 1
+	}
+	return calls, nil
+}
+
+// closingParen return the offset of the ')' matching the '(' at s[open]
+func closingParen(s string, open int) (int, error) {
+	depth := 0
+	quoted := false
+	for i := open; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == escapeRune:
+			i++
+		case c == quoteRune:
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	if quoted {
+		return 0, syntaxError(open, "unclosed quote")
+	}
+	return 0, syntaxError(open, "unclosed '('")
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isLetter(c) || ('0' <= c && c <= '9') || c == '_'
+}
+
+// IsName report whether s is a valid name of a validator or a group, it
+// starts with a letter and consists of letters, digits and '_'
+func IsName(s string) bool {
+	if s == "" || !isLetter(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Arg is an argument of a call
+type Arg struct {
+	Text   string // the argument without surrounding spaces
+	Offset int    // offset of Text
+}
+
+// SplitArgs splits the arguments of a call by the commas which are neither
+// quoted nor nested in parentheses.
+// example:
+//
+//	"Field, 'a,b', max(1, 2)" => ["Field" "'a,b'" "max(1, 2)"]
+func SplitArgs(s string) ([]Arg, error) {
+	return split(s, sepRune)
+}
+
+// split splits s by sep which is neither quoted nor nested in parentheses
+func split(s string, sep byte) ([]Arg, error) {
+	var (
+		args   []Arg
+		start  int
+		depth  int
+		open   int // offset of the outermost '(' not closed
+		quote  int // offset of the last quote opened
+		quoted bool
+	)
+	appendArg := func(end int) {
+		text := s[start:end]
+		trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+		offset := start + len(text) - len(trimmed)
+		args = append(args, Arg{Text: strings.TrimRightFunc(trimmed, unicode.IsSpace), Offset: offset})
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == escapeRune:
+			i++
+		case c == quoteRune:
+			quoted, quote = !quoted, i
+		case quoted:
+		case c == '(':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return nil, syntaxError(i, "unexpected ')'")
+			}
+		case c == sep && depth == 0:
+			appendArg(i)
+			start = i + 1
+		}
+	}
+	if quoted {
+		return nil, syntaxError(quote, "unclosed quote")
+	} else if depth > 0 {
+		return nil, syntaxError(open, "unclosed '('")
+	}
+	if strings.TrimSpace(s) != "" || len(args) > 0 {
+		appendArg(len(s))
+	}
+	return args, nil
+}
+
+const (
+	sectionSep = ';'
+	groupSep   = ':'
+)
+
+// Section is a part of a tag separated by ';', its rules apply to Group only
+// if Group is not empty.
+// example:
+//
+//	"not_empty(); update: len(36)" => [{"" "not_empty()"} {"update" "len(36)"}]
+type Section struct {
+	Group  string
+	Rules  string
+	Offset int // offset of Rules
+}
+
+// ParseSections splits a tag into sections
+func ParseSections(tag string) ([]Section, error) {
+	parts, err := split(tag, sectionSep)
+	if err != nil {
+		return nil, err
+	}
+	sections := make([]Section, 0, len(parts))
+	for _, part := range parts {
+		sec := Section{Rules: part.Text, Offset: part.Offset}
+		if i := strings.IndexByte(part.Text, groupSep); i >= 0 && !strings.ContainsAny(part.Text[:i], "('") {
+			group := strings.TrimSpace(part.Text[:i])
+			if !IsName(group) {
+				return nil, syntaxError(part.Offset, "invalid group name %q", group)
+			}
+			rules := part.Text[i+1:]
+			trimmed := strings.TrimLeftFunc(rules, unicode.IsSpace)
+			sec = Section{
+				Group:  group,
+				Rules:  trimmed,
+				Offset: part.Offset + i + 1 + len(rules) - len(trimmed),
+			}
+		}
+		sections = append(sections, sec)
+	}
+	return sections, nil
+}
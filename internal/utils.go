@@ -2,7 +2,6 @@ package internal
 
 import (
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -51,6 +50,9 @@ func ToUint64(args interface{}) (uint64, error) {
 		return strconv.ParseUint(v, 10, 64)
 	default:
 		val := reflect.ValueOf(args)
+		if val.Kind() == reflect.String {
+			return strconv.ParseUint(val.String(), 10, 64)
+		}
 		if val.Type().ConvertibleTo(typeOfUint64) {
 			return val.Convert(typeOfUint64).Uint(), nil
 		}
@@ -58,20 +60,12 @@ func ToUint64(args interface{}) (uint64, error) {
 	return 0, ErrInvalidValidatorSyntax
 }
 
-var vldPat = regexp.MustCompile(FnRegex)
-
-// ParseAllValidatorName return all validator name and its arguments in s
-// Note that validator name must start with letter
-// example:
-//     "range(1, 2, 3)" => ["range", "1, 2, 3"]
-func ParseAllValidatorName(s string) [][]string {
-	return vldPat.FindAllStringSubmatch(s, -1)
-}
-
 type ArgsInfos struct {
 	Strs []string
 	Ints []uint64
 	Vars []string
+	// VarOffsets holds the offset of each variable in Vars
+	VarOffsets []int
 }
 
 //         [,space]                         [,space]
@@ -106,13 +100,18 @@ func ParseArguments(s string) (*ArgsInfos, error) {
 		sb    = strings.Builder{}
 		state = initS
 
-		consts []string
-		strs   []string
-		ints   []uint64
-		err    error
+		consts     []string
+		constsOffs []int
+		strs       []string
+		ints       []uint64
+		err        error
+		tokenStart int
 	)
 
-	for _, r := range s {
+	for off, r := range s {
+		if state == initS {
+			tokenStart = off
+		}
 		switch state {
 		case initS:
 			err = handleInitS(&state, r, &i, &sb)
@@ -127,23 +126,27 @@ func ParseArguments(s string) (*ArgsInfos, error) {
 		}
 
 		if err != nil {
-			return nil, err
+			return nil, syntaxError(off, "unexpected %q", r)
+		}
+		if len(consts) > len(constsOffs) {
+			constsOffs = append(constsOffs, tokenStart)
 		}
 	}
 	// handle the final state so that state can transit to initS
 	switch state {
 	case constS:
 		consts = append(consts, sb.String())
+		constsOffs = append(constsOffs, tokenStart)
 	case intS:
 		ints = append(ints, i)
 	case strS, escapeS:
-		// unquoted string
-		return nil, ErrInvalidValidatorSyntax
+		return nil, syntaxError(tokenStart, "unclosed quote")
 	}
 	return &ArgsInfos{
-		Strs: strs,
-		Ints: ints,
-		Vars: consts,
+		Strs:       strs,
+		Ints:       ints,
+		Vars:       consts,
+		VarOffsets: constsOffs,
 	}, nil
 }
 
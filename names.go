@@ -0,0 +1,94 @@
+package xvalidator
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ccbhj/xvalidator/internal"
+	"github.com/pkg/errors"
+)
+
+// labelModifierName is the name of label('...'), it sets the name of a field
+// in the errors
+const labelModifierName string = "label"
+
+// NameResolver return the name of a field in the errors and the paths, the Go
+// name of the field is used if it return an empty string.
+type NameResolver func(field reflect.StructField) string
+
+// TagName return a NameResolver that reads the name of a field from the struct
+// tag key, like `json:"name,omitempty"`. The Go name is used if the name is
+// empty or "-".
+func TagName(key string) NameResolver {
+	return func(field reflect.StructField) string {
+		name := field.Tag.Get(key)
+		if i := strings.IndexByte(name, ','); i >= 0 {
+			name = name[:i]
+		}
+		if name == "-" {
+			return ""
+		}
+		return name
+	}
+}
+
+// JSONName reads the name of a field from the json tag
+func JSONName(field reflect.StructField) string {
+	return TagName("json")(field)
+}
+
+// FormName reads the name of a field from the form tag
+func FormName(field reflect.StructField) string {
+	return TagName("form")(field)
+}
+
+// XMLName reads the name of a field from the xml tag
+func XMLName(field reflect.StructField) string {
+	return TagName("xml")(field)
+}
+
+// YAMLName reads the name of a field from the yaml tag
+func YAMLName(field reflect.StructField) string {
+	return TagName("yaml")(field)
+}
+
+// WithNameResolver makes the Engine report the fields with the names
+// resolved by r, like JSONName, in ValidatorError.FieldName and the Path.
+// A label('...') in the rules of a field takes precedence in FieldName.
+func WithNameResolver(r NameResolver) Option {
+	return func(e *Engine) {
+		e.names = r
+	}
+}
+
+// fieldName return the name of field in the errors and the paths
+func (e *Engine) fieldName(field reflect.StructField) string {
+	if e.names != nil {
+		if name := e.names(field); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// takeLabel removes the label('...') from calls and return its argument
+func (c *compiler) takeLabel(ft fieldTag, calls []internal.Call) ([]internal.Call, string, error) {
+	label := ""
+	rest := make([]internal.Call, 0, len(calls))
+	for _, call := range calls {
+		if call.Name != labelModifierName {
+			rest = append(rest, call)
+			continue
+		}
+		arg, err := internal.ParseArguments(call.Args)
+		if err != nil {
+			return nil, "", c.tagError(ft, call.Name, syntaxOffset(err, call.ArgsOffset), err)
+		}
+		if label != "" || len(arg.Strs) != 1 || len(arg.Ints)+len(arg.Vars) > 0 || arg.Strs[0] == "" {
+			return nil, "", c.tagError(ft, call.Name, call.Offset,
+				errors.WithMessage(ErrInvalidArgument, "label() requires one string and can be used once"))
+		}
+		label = arg.Strs[0]
+	}
+	return rest, label, nil
+}
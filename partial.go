@@ -0,0 +1,120 @@
+package xvalidator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fieldFilter selects the fields of a struct to validate in a partial
+// validation, it is a tree of field names
+type fieldFilter struct {
+	except   bool
+	leaf     bool // the path ends here
+	children map[string]*fieldFilter
+}
+
+// field return the filter of the field named name, and whether the field
+// should be validated. A nil filter validates all the fields.
+func (f *fieldFilter) field(name string) (*fieldFilter, bool) {
+	if f == nil {
+		return nil, true
+	}
+	child, in := f.children[name]
+	switch {
+	case !in:
+		return nil, f.except
+	case child.leaf:
+		return nil, !f.except
+	}
+	return child, true
+}
+
+// partial report whether f filters out any field
+func (f *fieldFilter) partial() bool {
+	return f != nil && (len(f.children) > 0 || !f.except)
+}
+
+// newFieldFilter builds the filter of paths, a path is the dotted field names
+// from typ, like `Address.City`. The fields of the elements of a slice, an
+// array or a map are named like the fields of a struct, like `Items.SKU`.
+func newFieldFilter(typ reflect.Type, paths []string, except bool) (*fieldFilter, error) {
+	root := &fieldFilter{except: except}
+	for _, path := range paths {
+		if !fieldPathPat.MatchString(path) {
+			return nil, errors.WithMessagef(ErrInvalidArgument, "invalid field path %q", path)
+		}
+		node, t := root, typ
+		for _, name := range strings.Split(path, ".") {
+			if node.leaf {
+				break
+			}
+			elem := elemStruct(t)
+			if elem.Kind() != reflect.Struct {
+				return nil, errors.WithMessage(ErrUnknownField, path)
+			}
+			f, ok := elem.FieldByName(name)
+			if !ok || f.PkgPath != "" {
+				return nil, errors.WithMessage(ErrUnknownField, path)
+			}
+			t = f.Type
+			child, in := node.children[name]
+			if !in {
+				child = &fieldFilter{except: except}
+				if node.children == nil {
+					node.children = make(map[string]*fieldFilter)
+				}
+				node.children[name] = child
+			}
+			node = child
+		}
+		node.leaf, node.children = true, nil
+	}
+	return root, nil
+}
+
+// elemStruct return the type of the values that typ holds, through
+// pointers, slices, arrays and maps
+func elemStruct(typ reflect.Type) reflect.Type {
+	for {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			typ = typ.Elem()
+		default:
+			return typ
+		}
+	}
+}
+
+// ValidateFields validates only the fields at paths of a struct pointer or
+// struct value, like ValidateStruct. A path is the dotted Go field names like
+// `Address.City`, the fields in the elements of a slice or a map are named like
+// the fields of a struct, like `Items.SKU`.
+// The rules of the fields on a path, like the rules of Address, are validated
+// as well. The Validatable hooks of the structs that are partially validated
+// are not called.
+func (e *Engine) ValidateFields(strct interface{}, paths ...string) error {
+	return e.validatePartial(strct, paths, false)
+}
+
+// ValidateExcept validates all the fields of a struct pointer or struct value
+// except the fields at paths, see ValidateFields for the paths.
+func (e *Engine) ValidateExcept(strct interface{}, paths ...string) error {
+	return e.validatePartial(strct, paths, true)
+}
+
+func (e *Engine) validatePartial(strct interface{}, paths []string, except bool) error {
+	typ, err := structType(strct)
+	if err != nil {
+		return err
+	}
+	filter, err := newFieldFilter(typ, paths, except)
+	if err != nil {
+		return err
+	}
+	r := e.newRun(context.Background(), false)
+	r.filter = filter
+	return e.validateStruct(r, strct, "")
+}
@@ -0,0 +1,139 @@
+package xvalidator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SegmentKind is the kind of a PathSegment
+type SegmentKind int
+
+const (
+	// FieldSegment is a struct field
+	FieldSegment SegmentKind = iota
+	// IndexSegment is an element of a slice or an array
+	IndexSegment
+	// KeySegment is an entry of a map
+	KeySegment
+)
+
+// PathSegment is a step from a value into one of its elements
+type PathSegment struct {
+	Kind  SegmentKind
+	Name  string      // field name of a FieldSegment
+	Index int         // index of an IndexSegment
+	Key   interface{} // map key of a KeySegment
+}
+
+func fieldSegment(name string) PathSegment {
+	return PathSegment{Kind: FieldSegment, Name: name}
+}
+
+func indexSegment(i int) PathSegment {
+	return PathSegment{Kind: IndexSegment, Index: i}
+}
+
+func keySegment(key interface{}) PathSegment {
+	return PathSegment{Kind: KeySegment, Key: key}
+}
+
+// Path locates a value from the root of a validation, like
+// Order.Items[3].SKU
+type Path []PathSegment
+
+// lastField return the name of the last field in p
+func (p Path) lastField() string {
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i].Kind == FieldSegment {
+			return p[i].Name
+		}
+	}
+	return ""
+}
+
+// String return p in dotted form like `Order.Items[3].SKU` or
+// `Labels["env"]`
+func (p Path) String() string {
+	var sb strings.Builder
+	for i, seg := range p {
+		switch seg.Kind {
+		case FieldSegment:
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(seg.Name)
+		case IndexSegment:
+			fmt.Fprintf(&sb, "[%d]", seg.Index)
+		case KeySegment:
+			if s, ok := seg.Key.(string); ok {
+				fmt.Fprintf(&sb, "[%s]", strconv.Quote(s))
+			} else {
+				fmt.Fprintf(&sb, "[%v]", seg.Key)
+			}
+		}
+	}
+	return sb.String()
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// JSONPointer return p in the form of RFC 6901 JSON Pointer like
+// `/Order/Items/3/SKU`, an empty Path is the whole document.
+func (p Path) JSONPointer() string {
+	var sb strings.Builder
+	for _, seg := range p {
+		sb.WriteByte('/')
+		switch seg.Kind {
+		case FieldSegment:
+			sb.WriteString(jsonPointerEscaper.Replace(seg.Name))
+		case IndexSegment:
+			sb.WriteString(strconv.Itoa(seg.Index))
+		case KeySegment:
+			sb.WriteString(jsonPointerEscaper.Replace(fmt.Sprint(seg.Key)))
+		}
+	}
+	return sb.String()
+}
+
+// parsePath parses a path in dotted form, see Path.String. It return false if
+// s is malformed.
+func parsePath(s string) (Path, bool) {
+	var p Path
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, false
+			}
+			inner := s[i+1 : i+end]
+			if strings.HasPrefix(inner, `"`) {
+				key, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, false
+				}
+				p = append(p, keySegment(key))
+			} else if idx, err := strconv.Atoi(inner); err == nil && idx >= 0 {
+				p = append(p, indexSegment(idx))
+			} else {
+				return nil, false
+			}
+			i += end + 1
+		case s[i] == '.' && len(p) > 0:
+			i++
+			fallthrough
+		default:
+			end := strings.IndexAny(s[i:], ".[")
+			if end < 0 {
+				end = len(s) - i
+			}
+			if end == 0 {
+				return nil, false
+			}
+			p = append(p, fieldSegment(s[i:i+end]))
+			i += end
+		}
+	}
+	return p, true
+}
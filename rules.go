@@ -0,0 +1,160 @@
+package xvalidator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// rule is a compiled check of a single value
+type rule interface {
+	check(r *run, v reflect.Value) error
+}
+
+// funcRule runs a Validator created by a registered factory against the value
+// that v points to
+// A nil pointer or a nil interface fails with a ValidatorError, use
+// omitempty() to make a field optional.
+type funcRule struct {
+	fn ValidatorCtx
+}
+
+func (f funcRule) check(r *run, v reflect.Value) error {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ValidatorError{Reason: "nil value"}
+	}
+	return f.fn(r.ctx, v.Interface())
+}
+
+// requiredRule rejects nil pointers, nil slices, nil maps, nil interfaces and
+// zero values
+type requiredRule struct{}
+
+func (requiredRule) check(_ *run, v reflect.Value) error {
+	if isEmpty(v) {
+		return ValidatorError{Reason: "required"}
+	}
+	return nil
+}
+
+// omitEmptyRule skips its rules if the value is empty, see isEmpty
+type omitEmptyRule struct {
+	rules []rule
+}
+
+func (o omitEmptyRule) check(r *run, v reflect.Value) error {
+	if isEmpty(v) {
+		return nil
+	}
+	return r.checkRules(o.rules, v)
+}
+
+// isEmpty report whether v is nil or the zero value of its type
+func isEmpty(v reflect.Value) bool {
+	return !v.IsValid() || v.IsZero()
+}
+
+// indirect follows pointers and interfaces until it reaches a concrete value,
+// it return an invalid Value if a nil is in the way.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// structRule validates a nested struct, or every struct in a slice or array,
+// with the compiled rules of the struct
+// Nil pointers are skipped.
+type structRule struct {
+	cs *compiledStruct
+}
+
+func (s structRule) check(r *run, v reflect.Value) error {
+	if isNil(v) {
+		return nil
+	}
+	if k := reflect.Indirect(v).Kind(); k != reflect.Slice && k != reflect.Array {
+		return s.cs.check(r, v)
+	}
+	return eachRule{rules: []rule{s}}.check(r, v)
+}
+
+// eachRule applies its rules to every element of a slice or an array
+type eachRule struct {
+	rules []rule
+}
+
+func (e eachRule) check(r *run, v reflect.Value) error {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	var errs ValidationErrors
+	for i := 0; i < v.Len(); i++ {
+		if err := r.apply(&errs, e.rules, v.Index(i), indexSegment(i), ""); err != nil {
+			return err
+		}
+		if r.full() {
+			break
+		}
+	}
+	return errs.orNil()
+}
+
+// mapRule applies its rules to every key or every value of a map in the order
+// of the sorted keys
+type mapRule struct {
+	rules []rule
+	keys  bool // apply to keys instead of values
+}
+
+func (m mapRule) check(r *run, v reflect.Value) error {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	var errs ValidationErrors
+	for _, key := range sortedKeys(v) {
+		target := key
+		if !m.keys {
+			target = v.MapIndex(key)
+		}
+		if err := r.apply(&errs, m.rules, target, keySegment(key.Interface()), ""); err != nil {
+			return err
+		}
+		if r.full() {
+			break
+		}
+	}
+	return errs.orNil()
+}
+
+// sortedKeys return the keys of map v in a stable order
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return lessValue(keys[i], keys[j])
+	})
+	return keys
+}
+
+func lessValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
@@ -0,0 +1,425 @@
+package xvalidator
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// schemaType is the type of a value in a document, it is declared by the
+// first call in the rules of a key, like `string(), len(36)`
+type schemaType struct {
+	name string
+	typ  reflect.Type // the Go type the rules are compiled against
+	// convert return the value in typ, or false if v is not of this type
+	convert func(v reflect.Value) (reflect.Value, bool)
+}
+
+var schemaTypes = map[string]schemaType{
+	"string": {"a string", reflect.TypeOf(""), convertKind(reflect.String)},
+	"bool":   {"a bool", reflect.TypeOf(false), convertKind(reflect.Bool)},
+	"number": {"a number", reflect.TypeOf(float64(0)), convertNumber},
+	"int":    {"an integer", reflect.TypeOf(int64(0)), convertInt},
+	"object": {"an object", reflect.TypeOf(map[string]interface{}{}), convertObject},
+	"array":  {"an array", reflect.TypeOf([]interface{}{}), convertArray},
+}
+
+func convertKind(k reflect.Kind) func(v reflect.Value) (reflect.Value, bool) {
+	return func(v reflect.Value) (reflect.Value, bool) {
+		return v, v.Kind() == k
+	}
+}
+
+func convertNumber(v reflect.Value) (reflect.Value, bool) {
+	if !isNumber(v.Kind()) {
+		return v, false
+	}
+	return reflect.ValueOf(toFloat(v)), true
+}
+
+func convertInt(v reflect.Value) (reflect.Value, bool) {
+	switch k := v.Kind(); {
+	case isInt(k):
+		return reflect.ValueOf(v.Int()), true
+	case isUint(k) && v.Uint() <= math.MaxInt64:
+		return reflect.ValueOf(int64(v.Uint())), true
+	case k == reflect.Float32 || k == reflect.Float64:
+		f := v.Float()
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return reflect.ValueOf(int64(f)), true
+		}
+	}
+	return v, false
+}
+
+func convertObject(v reflect.Value) (reflect.Value, bool) {
+	return v, v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String
+}
+
+func convertArray(v reflect.Value) (reflect.Value, bool) {
+	return v, v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+}
+
+// Schema validates documents like map[string]interface{} decoded from JSON
+// without Go structs, see Engine.CompileSchema.
+type Schema struct {
+	e             *Engine
+	root          *schemaNode
+	strictObjects bool
+}
+
+// SchemaOption configures a Schema
+type SchemaOption func(*Schema)
+
+// WithUnknownKeys makes a Schema report the keys of an object that are not in
+// the schema, the objects without keys in the schema are not checked.
+func WithUnknownKeys() SchemaOption {
+	return func(s *Schema) {
+		s.strictObjects = true
+	}
+}
+
+// schemaNode holds the rules of a key path and the rules of the paths under it
+type schemaNode struct {
+	declared bool // the path has rules
+	optional bool // the path starts with omitempty()
+	required bool // the path or any path under it must be present
+	typ      *schemaType
+	rules    []rule
+
+	children map[string]*schemaNode // keys of an object
+	anyKey   *schemaNode            // `*`, every key of an object
+	elem     *schemaNode            // `[*]`, every element of an array
+}
+
+// anyKeySeg and elemSeg are the wildcards of a key path
+const (
+	anyKeySeg = "*"
+	elemSeg   = "[*]"
+)
+
+// CompileSchema compiles rules into a Schema, rules maps a key path to rules
+// written like a 'xvldt' tag. A key path is the keys of nested objects joined
+// by '.', `*` matches every key of an object and `[*]` every element of an
+// array:
+//
+//	"user.email":     "string(), not_empty()"
+//	"items[*].sku":   "string(), len(8)"
+//	"labels.*":       "string(), omitempty(), regex('^[a-z]+$')"
+//
+// The first call can declare the type of the value: string(), bool(),
+// number(), int(), object() or array(), the rest of the rules are compiled
+// against string, bool, float64, int64, map[string]interface{} or
+// []interface{} respectively, values of other types are reported with
+// ErrTypeMismatch. Without a type, the rules are compiled against
+// interface{}.
+// A key in the schema is required unless its rules start with omitempty(),
+// a missing key is reported with ErrMissingKey.
+func (e *Engine) CompileSchema(rules map[string]string, opts ...SchemaOption) (*Schema, error) {
+	s := &Schema{e: e, root: &schemaNode{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	paths := make([]string, 0, len(rules))
+	for path := range rules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	c := &compiler{e: e, reg: e.load(), pending: make(map[reflect.Type]*compiledStruct)}
+	for _, path := range paths {
+		segs, ok := splitKeyPath(path)
+		if !ok {
+			return nil, errors.WithMessagef(ErrInvalidArgument, "invalid key path %q", path)
+		}
+		node := s.root
+		for _, seg := range segs {
+			node = node.child(seg)
+		}
+		if err := c.compileSchemaNode(node, path, rules[path]); err != nil {
+			return nil, err
+		}
+	}
+	for _, cs := range c.onDemand {
+		e.cache.LoadOrStore(structKey{typ: cs.typ}, cs)
+	}
+	s.root.markRequired()
+	return s, nil
+}
+
+// splitKeyPath splits a key path into keys and wildcards
+func splitKeyPath(path string) ([]string, bool) {
+	var segs []string
+	for i := 0; i < len(path); {
+		if strings.HasPrefix(path[i:], elemSeg) {
+			segs = append(segs, elemSeg)
+			i += len(elemSeg)
+			continue
+		}
+		if path[i] == '.' {
+			if len(segs) == 0 {
+				return nil, false
+			}
+			i++
+		}
+		end := strings.IndexAny(path[i:], ".[")
+		if end < 0 {
+			end = len(path) - i
+		}
+		if end == 0 {
+			return nil, false
+		}
+		segs = append(segs, path[i:i+end])
+		i += end
+	}
+	return segs, len(segs) > 0
+}
+
+func (n *schemaNode) child(seg string) *schemaNode {
+	next := &n.elem
+	switch seg {
+	case elemSeg:
+	case anyKeySeg:
+		next = &n.anyKey
+	default:
+		if n.children == nil {
+			n.children = make(map[string]*schemaNode)
+		}
+		if child, in := n.children[seg]; in {
+			return child
+		}
+		child := &schemaNode{}
+		n.children[seg] = child
+		return child
+	}
+	if *next == nil {
+		*next = &schemaNode{}
+	}
+	return *next
+}
+
+// markRequired fills required of n and the nodes under it
+func (n *schemaNode) markRequired() bool {
+	n.required = n.declared && !n.optional
+	for _, child := range n.children {
+		if child.markRequired() && !n.optional {
+			n.required = true
+		}
+	}
+	if n.anyKey != nil {
+		n.anyKey.markRequired()
+	}
+	if n.elem != nil {
+		n.elem.markRequired()
+	}
+	return n.required
+}
+
+// compileSchemaNode compiles the rules of a key path into node
+func (c *compiler) compileSchemaNode(node *schemaNode, path, rules string) error {
+	ft := fieldTag{field: reflect.StructField{Name: path, Type: interfaceType}, tag: rules}
+	calls, err := c.parseCalls(ft, rules, 0)
+	if err != nil {
+		return err
+	}
+	node.declared = true
+	typ := interfaceType
+	if len(calls) > 0 {
+		if st, in := schemaTypes[calls[0].Name]; in {
+			if err := c.noArgs(ft, calls[0]); err != nil {
+				return err
+			}
+			node.typ, typ = &st, st.typ
+			calls = calls[1:]
+		}
+	}
+	node.optional = len(calls) > 0 && calls[0].Name == omitEmptyModifierName
+	node.rules, err = c.compileCalls(ft, calls, typ)
+	return err
+}
+
+// ValidateMap compiles rules into a Schema and validates doc with it, see
+// CompileSchema. Use CompileSchema to validate many documents with the same
+// rules.
+func (e *Engine) ValidateMap(doc interface{}, rules map[string]string, opts ...SchemaOption) error {
+	s, err := e.CompileSchema(rules, opts...)
+	if err != nil {
+		return err
+	}
+	return s.Validate(doc)
+}
+
+// Validate validates doc, which is usually a map[string]interface{} or a
+// []interface{}, with the settings of the Engine of s.
+func (s *Schema) Validate(doc interface{}) error {
+	return s.validate(s.e.newRun(context.Background(), false), doc)
+}
+
+// ValidateAll validates doc like Validate, but keep going after a rule fails
+// and return all the errors as a ValidationErrors.
+func (s *Schema) ValidateAll(doc interface{}) error {
+	return s.validate(s.e.newRun(context.Background(), true), doc)
+}
+
+func (s *Schema) validate(r *run, doc interface{}) error {
+	err := s.check(r, s.root, reflect.ValueOf(doc))
+	if r.abort != nil {
+		return r.abort
+	}
+	if es, ok := err.(ValidationErrors); ok && r.max > 0 && len(es) > r.max {
+		err = es[:r.max]
+	}
+	return err
+}
+
+// schemaError return the error of a document, it is located by the caller
+func schemaError(err error, format string, args ...interface{}) error {
+	return ValidatorError{Reason: errors.Errorf(format, args...).Error(), Err: err}
+}
+
+// check validates v with node and the nodes under it
+func (s *Schema) check(r *run, node *schemaNode, v reflect.Value) error {
+	v = indirect(v)
+	if node.declared {
+		if !v.IsValid() && node.optional {
+			return nil
+		}
+		if node.typ != nil {
+			converted, ok := reflect.Value{}, v.IsValid()
+			if ok {
+				converted, ok = node.typ.convert(v)
+			}
+			if !ok {
+				return r.fail(schemaError(ErrTypeMismatch, "must be %s, got %s", node.typ.name, typeName(v)))
+			}
+			v = converted
+		}
+		if err := r.checkRules(node.rules, v); err != nil {
+			return err
+		}
+	}
+	if r.full() || (node.children == nil && node.anyKey == nil && node.elem == nil) {
+		return nil
+	}
+
+	var errs ValidationErrors
+	each := func(seg PathSegment, child *schemaNode, elem reflect.Value) error {
+		err := s.check(r, child, elem)
+		if err == nil {
+			return nil
+		}
+		if r.abort != nil {
+			return r.abort
+		}
+		err = locate(err, seg)
+		if !r.all {
+			return err
+		}
+		errs = append(errs, err.(ValidationErrors)...)
+		return nil
+	}
+
+	if node.elem != nil {
+		if k := v.Kind(); k != reflect.Slice && k != reflect.Array {
+			if v.IsValid() || node.elem.required {
+				return r.fail(schemaError(ErrTypeMismatch, "must be an array, got %s", typeName(v)))
+			}
+			return nil
+		}
+		for i := 0; i < v.Len() && !r.full(); i++ {
+			if err := each(indexSegment(i), node.elem, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return errs.orNil()
+	}
+
+	if _, ok := convertObject(v); !ok {
+		if v.IsValid() || node.requiredUnder() {
+			return r.fail(schemaError(ErrTypeMismatch, "must be an object, got %s", typeName(v)))
+		}
+		return nil
+	}
+	for _, key := range sortedKeys(v) {
+		name := key.String()
+		child, in := node.children[name]
+		if !in {
+			child = node.anyKey
+		}
+		if child == nil {
+			if s.strictObjects && node.children != nil {
+				if err := r.failAt(&errs, fieldSegment(name), schemaError(ErrUnknownKey, "unknown key")); err != nil {
+					return err
+				}
+			}
+		} else if err := each(fieldSegment(name), child, v.MapIndex(key)); err != nil {
+			return err
+		}
+		if r.full() {
+			return errs.orNil()
+		}
+	}
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !node.children[name].required || v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())).IsValid() {
+			continue
+		}
+		if err := r.failAt(&errs, fieldSegment(name), schemaError(ErrMissingKey, "missing key")); err != nil {
+			return err
+		}
+		if r.full() {
+			break
+		}
+	}
+	return errs.orNil()
+}
+
+// requiredUnder report whether any path under n is required
+func (n *schemaNode) requiredUnder() bool {
+	for _, child := range n.children {
+		if child.required {
+			return true
+		}
+	}
+	return false
+}
+
+// fail return err in fail-fast mode or collected as a ValidationErrors
+func (r *run) fail(err error) error {
+	if !r.all {
+		return err
+	}
+	return r.collect(nil, err)
+}
+
+// failAt locates err with seg and collects it into errs, err is returned in
+// fail-fast mode
+func (r *run) failAt(errs *ValidationErrors, seg PathSegment, err error) error {
+	err = locate(err, seg)
+	if !r.all {
+		return err
+	}
+	*errs = r.collect(*errs, err)
+	return nil
+}
+
+// typeName return the name of the type of v in a document
+func typeName(v reflect.Value) string {
+	if !v.IsValid() {
+		return "null"
+	}
+	for _, name := range []string{"string", "bool", "number", "object", "array"} {
+		if _, ok := schemaTypes[name].convert(v); ok {
+			return name
+		}
+	}
+	return v.Type().String()
+}
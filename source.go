@@ -0,0 +1,47 @@
+package xvalidator
+
+import (
+	"reflect"
+)
+
+// RuleSource provides the rules of struct fields, the rules are written like a
+// 'xvldt' tag and "-" skips a field.
+type RuleSource interface {
+	// Rules return the rules of field, which is declared in strct, and
+	// whether field has rules.
+	Rules(strct reflect.Type, field reflect.StructField) (string, bool)
+}
+
+// TagRules return a RuleSource that reads the rules from the struct tag name
+func TagRules(name string) RuleSource {
+	return tagRules(name)
+}
+
+type tagRules string
+
+func (t tagRules) Rules(_ reflect.Type, field reflect.StructField) (string, bool) {
+	return field.Tag.Lookup(string(t))
+}
+
+// RuleTable is a RuleSource for the structs that cannot be tagged, like the
+// structs of other packages. It maps a struct type to the rules of its fields
+// by name, the fields promoted from an embedded struct are looked up with the
+// embedded struct type.
+type RuleTable map[reflect.Type]map[string]string
+
+func (t RuleTable) Rules(strct reflect.Type, field reflect.StructField) (string, bool) {
+	rules, in := t[strct][field.Name]
+	return rules, in
+}
+
+// ruleSources tries each RuleSource in order
+type ruleSources []RuleSource
+
+func (s ruleSources) Rules(strct reflect.Type, field reflect.StructField) (string, bool) {
+	for _, src := range s {
+		if rules, has := src.Rules(strct, field); has {
+			return rules, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,297 @@
+package xvalidator
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// compiledStruct holds the rules of every tagged field of a struct type
+type compiledStruct struct {
+	typ    reflect.Type
+	group  string // validation group, empty if compiled without a group
+	fields []compiledField
+	hook   rule // calls Validatable or ValidatableCtx, nil if not implemented
+}
+
+// structKey is the key of a struct compiled for a validation group
+type structKey struct {
+	typ   reflect.Type
+	group string
+}
+
+// compiledField holds the rules of a struct field in the order of its tag
+type compiledField struct {
+	index []int  // index path, see reflect.Value.FieldByIndex
+	field string // Go name of the field
+	name  string // name of the field in the paths, see NameResolver
+	label string // name of the field in the errors, see label()
+	rules []rule
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but return false if a nil
+// embedded struct pointer is in the way
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// run holds the state of a single validation
+type run struct {
+	ctx context.Context
+	// abort is the error that stops the whole validation, like the error of
+	// a done context, it is never collected.
+	abort error
+
+	all bool // keep going after the first error
+	max int  // stop collecting after max errors, 0 means no limit
+	n   int  // number of errors collected so far
+
+	depth int // number of nested structs being validated
+	// strct is the struct whose fields are being validated, it is used to
+	// look up the sibling fields
+	strct    reflect.Value
+	maxDepth int
+	// filter selects the fields of strct to validate, nil means all
+	filter *fieldFilter
+	// visiting holds the struct pointers being validated, so that a pointer
+	// cycle is only validated once
+	visiting map[visit]struct{}
+}
+
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+func (e *Engine) newRun(ctx context.Context, all bool) *run {
+	return &run{
+		ctx:      ctx,
+		all:      all || e.allErrors,
+		max:      e.maxErrors,
+		maxDepth: e.maxDepth,
+	}
+}
+
+// enter marks val as being validated, it return false if val is already
+// being validated by the caller or r is too deep.
+func (r *run) enter(val reflect.Value) (bool, error) {
+	if r.maxDepth > 0 && r.depth >= r.maxDepth {
+		return false, ErrMaxDepth
+	}
+	if val.Kind() == reflect.Ptr {
+		key := visit{ptr: val.Pointer(), typ: val.Type()}
+		if _, in := r.visiting[key]; in {
+			return false, nil
+		}
+		if r.visiting == nil {
+			r.visiting = make(map[visit]struct{})
+		}
+		r.visiting[key] = struct{}{}
+	}
+	r.depth++
+	return true, nil
+}
+
+// leave undo what enter did
+func (r *run) leave(val reflect.Value) {
+	r.depth--
+	if val.Kind() == reflect.Ptr {
+		delete(r.visiting, visit{ptr: val.Pointer(), typ: val.Type()})
+	}
+}
+
+// full report whether r has collected enough errors
+func (r *run) full() bool {
+	return r.max > 0 && r.n >= r.max
+}
+
+// apply runs rules against v and locates the errors with seg and name, see
+// locateAs. The first error is returned in fail-fast mode, otherwise errors are
+// collected into errs.
+// The caller should stop if r is full after apply returns.
+func (r *run) apply(errs *ValidationErrors, rules []rule, v reflect.Value, seg PathSegment, name string) error {
+	err := r.checkRules(rules, v)
+	if err == nil {
+		return nil
+	}
+	if r.abort != nil {
+		return r.abort
+	}
+	if !r.all {
+		return locateAs(err, seg, name)
+	}
+	*errs = append(*errs, locateAs(err, seg, name).(ValidationErrors)...)
+	return nil
+}
+
+// checkRules runs rules against v in order. The first error is returned in
+// fail-fast mode, otherwise all the errors are returned as a ValidationErrors.
+func (r *run) checkRules(rules []rule, v reflect.Value) error {
+	var errs ValidationErrors
+	for _, rl := range rules {
+		err := rl.check(r, v)
+		if err == nil {
+			continue
+		}
+		if ctxErr := r.ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			r.abort = ctxErr
+		}
+		if r.abort != nil {
+			return r.abort
+		}
+		if !r.all {
+			return err
+		}
+		errs = r.collect(errs, err)
+		if r.full() {
+			break
+		}
+	}
+	return errs.orNil()
+}
+
+// collect append err into errs, err will be converted into ValidatorError if
+// it is not.
+func (r *run) collect(errs ValidationErrors, err error) ValidationErrors {
+	if _, ok := err.(ValidationErrors); !ok {
+		r.n++
+	}
+	return append(errs, validationErrors(err)...)
+}
+
+// validationErrors converts err into ValidationErrors
+func validationErrors(err error) ValidationErrors {
+	if es, ok := err.(ValidationErrors); ok {
+		return es
+	}
+	var ve ValidatorError
+	if !errors.As(err, &ve) {
+		ve = ValidatorError{Reason: err.Error(), Err: err}
+	}
+	return ValidationErrors{ve}
+}
+
+// locate prepend seg to the path of every ValidatorError in err, the field
+// name will be filled with the name of seg if it is empty
+func locate(err error, seg PathSegment) error {
+	name := ""
+	if seg.Kind == FieldSegment {
+		name = seg.Name
+	}
+	return locateAs(err, seg, name)
+}
+
+// locateAs is like locate but fills the field name with name
+func locateAs(err error, seg PathSegment, name string) error {
+	if es, ok := err.(ValidationErrors); ok {
+		for i := range es {
+			es[i] = locateAs(es[i], seg, name).(ValidatorError)
+		}
+		return es
+	}
+	var e ValidatorError
+	if !errors.As(err, &e) {
+		return err
+	}
+	if e.FieldName == "" {
+		e.FieldName = name
+	}
+	e.Path = append(Path{seg}, e.Path...)
+	return e
+}
+
+// check validates all the fields of val
+// In all-errors mode, a ValidationErrors will be returned.
+// A struct pointer that is already being validated in r will be skipped, so
+// that pointer cycles terminate.
+func (cs *compiledStruct) check(r *run, val reflect.Value) error {
+	if val.Kind() == reflect.Interface {
+		val = val.Elem()
+	}
+	if !reflect.Indirect(val).IsValid() {
+		return ErrInvalidStruct
+	}
+	ok, err := r.enter(val)
+	if !ok {
+		return err
+	}
+	defer r.leave(val)
+
+	val = reflect.Indirect(val)
+	defer func(strct reflect.Value, filter *fieldFilter) {
+		r.strct, r.filter = strct, filter
+	}(r.strct, r.filter)
+	r.strct = val
+	filter := r.filter
+
+	var errs ValidationErrors
+	for _, f := range cs.fields {
+		if err := r.ctx.Err(); err != nil {
+			r.abort = err
+			return err
+		}
+		sub, selected := filter.field(f.field)
+		if !selected {
+			continue
+		}
+		field, ok := fieldByIndex(val, f.index)
+		if !ok || !field.CanInterface() {
+			continue
+		}
+		r.filter = sub
+		if err := r.apply(&errs, f.rules, field, fieldSegment(f.name), f.label); err != nil {
+			return err
+		}
+		if r.full() {
+			break
+		}
+	}
+	// the hook runs after the fields, its errors belong to the struct itself
+	if cs.hook != nil && !r.full() && !filter.partial() {
+		r.filter = nil
+		if err := r.checkRules([]rule{cs.hook}, val); err != nil {
+			if r.abort != nil || !r.all {
+				return err
+			}
+			errs = append(errs, err.(ValidationErrors)...)
+		}
+	}
+	return errs.orNil()
+}
+
+// validate runs a new validation of v
+func (cs *compiledStruct) validate(r *run, v interface{}) error {
+	err := cs.check(r, reflect.ValueOf(v))
+	if r.abort != nil {
+		return r.abort
+	}
+	if es, ok := err.(ValidationErrors); ok && r.max > 0 && len(es) > r.max {
+		err = es[:r.max]
+	}
+	return err
+}
+
+// validator return cs as a Validator using the settings of e
+func (cs *compiledStruct) validator(e *Engine) Validator {
+	return func(v interface{}) error {
+		return cs.validate(e.newRun(context.Background(), false), v)
+	}
+}
@@ -1,6 +1,7 @@
 package xvalidator
 
 import (
+	"context"
 	"reflect"
 	"regexp"
 	"strings"
@@ -9,21 +10,47 @@ import (
 	"github.com/pkg/errors"
 )
 
-var registeredStruct = make(map[reflect.Type]Validator)
-var registeredConstInt = make(map[string]uint64)
-var registeredConstStr = make(map[string]string)
-var registeredValidator = make(map[string]func(args ValidatorArgs) Validator)
-
 const DefaultTagName = "xvldt"
 
 type ValidatorArgs struct {
 	Strs []string
 	Ints []uint64
 	Typ  reflect.Type
+
+	engine *Engine
 }
 
 type Validator func(interface{}) error
 
+// ValidatorFactory creates a Validator from the arguments in a tag, it should
+// return an error rather than panicking if the arguments are invalid.
+type ValidatorFactory func(args ValidatorArgs) (Validator, error)
+
+// ValidatorCtx is a Validator that can access the context of the validation,
+// like request-scoped data, and should stop when the context is done.
+type ValidatorCtx func(ctx context.Context, v interface{}) error
+
+// ValidatorCtxFactory creates a ValidatorCtx from the arguments in a tag
+type ValidatorCtxFactory func(args ValidatorArgs) (ValidatorCtx, error)
+
+// withCtx adapts v into a ValidatorCtx that ignores the context
+func (v Validator) withCtx() ValidatorCtx {
+	if v == nil {
+		return nil
+	}
+	return func(_ context.Context, i interface{}) error {
+		return v(i)
+	}
+}
+
+// withCtx adapts f into a ValidatorCtxFactory
+func (f ValidatorFactory) withCtx() ValidatorCtxFactory {
+	return func(args ValidatorArgs) (ValidatorCtx, error) {
+		vld, err := f(args)
+		return vld.withCtx(), err
+	}
+}
+
 func dummyValidator(interface{}) error {
 	return nil
 }
@@ -67,90 +94,45 @@ func (v Validator) WithName(s string) Validator {
 }
 
 // NewStructValidator parse the 'xvldt' tag in struct's fields and return a new
-// Validator.
+// Validator using the validators and constants registered in e.
 // All validator can be seperated with ',', and must carry '()' even if the
 // validator need no arguments.
-func NewStructValidator(args interface{}) Validator {
-	val := reflect.Indirect(reflect.ValueOf(args))
-	if val.Kind() != reflect.Struct {
-		panic(errors.WithMessage(ErrInvalidValidatorArgument, "must be struct or struct pointer"))
+// NewStructValidator panics if any tag is invalid, use CompileStruct to get an
+// error instead.
+func (e *Engine) NewStructValidator(args interface{}) Validator {
+	vld, err := e.CompileStruct(args)
+	if err != nil {
+		panic(err)
 	}
+	return vld
+}
 
-	typ := val.Type()
-	vlds := make([]Validator, typ.NumField())
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		tag, has := field.Tag.Lookup(DefaultTagName)
-		if !has {
-			vlds[i] = nil
-			continue
-		}
-		// parse all the validator name and arguments
-		var vld Validator
-		for _, match := range internal.ParseAllValidatorName(tag) {
-			fn := strings.TrimSpace(match[1])
-			argStr := strings.TrimSpace(match[2])
-			v, in := registeredValidator[fn]
-			if !in {
-				panic(ErrUnknownValidator)
-			}
-			arg, err := internal.ParseArguments(argStr)
-			if err != nil {
-				panic(err)
-			}
-
-			// replace the variable with the registered value
-			for _, v := range arg.Vars {
-				ic, in := registeredConstInt[v]
-				if in {
-					arg.Ints = append(arg.Ints, ic)
-					break
-				}
-				sc, in := registeredConstStr[v]
-				if !in {
-					panic(errors.WithMessage(ErrUnknownConst, v))
-				}
-				arg.Strs = append(arg.Strs, sc)
-			}
-
-			typ := field.Type
-			if typ.Kind() == reflect.Ptr {
-				typ = typ.Elem()
-			}
-			va := ValidatorArgs{
-				Strs: arg.Strs,
-				Ints: arg.Ints,
-				Typ:  typ,
-			}
+// newStringRangeValidator is StringRangeValidator that rejects the types
+// other than string when compiling
+func newStringRangeValidator(arg ValidatorArgs) (Validator, error) {
+	if err := stringType(arg, stringRangeValidatorName); err != nil {
+		return nil, err
+	}
+	return StringRangeValidator(arg), nil
+}
 
-			if vld == nil {
-				vld = v(va)
-			} else {
-				vld = vld.And(v(va))
-			}
-		}
-		vlds[i] = vld.WithName(field.Name)
+// stringType checks that a validator of strings is compiled for a string or
+// an interface which can only be checked at runtime
+func stringType(arg ValidatorArgs, name string) error {
+	if arg.Typ != nil && arg.Typ.Kind() != reflect.String && arg.Typ.Kind() != reflect.Interface {
+		return errors.WithMessagef(ErrInvalidArgument, "invalid type %s for %s validator", arg.Typ, name)
 	}
+	return nil
+}
 
-	return func(arg interface{}) error {
-		val := reflect.Indirect(reflect.ValueOf(arg))
-		if !val.IsValid() {
-			return ErrInvalidStruct
-		}
-		for i, vld := range vlds {
-			if vld == nil {
-				continue
-			}
-			field := reflect.Indirect(val.Field(i))
-			if !field.CanInterface() {
-				continue
-			}
-			if err := vld(field.Interface()); err != nil {
-				return err
-			}
-		}
-		return nil
+// stringValue return the string held by arg, arg can be of any type whose kind
+// is string, like a named string type
+func stringValue(arg interface{}) (string, error) {
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.String {
+		return "", ValidatorError{Reason: "not a string"}
 	}
+	return v.String(), nil
 }
 
 // StringRangeValidator return a Validator that check whether a string value in a
@@ -161,9 +143,9 @@ func StringRangeValidator(arg ValidatorArgs) Validator {
 		v[x] = struct{}{}
 	}
 	return func(arg interface{}) error {
-		s, ok := arg.(string)
-		if !ok {
-			return errors.New("not a string")
+		s, err := stringValue(arg)
+		if err != nil {
+			return err
 		}
 		_, in := v[s]
 		if !in {
@@ -200,8 +182,12 @@ func IntRangeValidator(arg ValidatorArgs) Validator {
 // MaxValidator return a Validator that check whether an integer is less than the
 // first arguments of the validator
 func MaxValidator(arg ValidatorArgs) Validator {
+	return mustValidator(newMaxValidator(arg))
+}
+
+func newMaxValidator(arg ValidatorArgs) (Validator, error) {
 	if len(arg.Ints) < 1 {
-		panic(errors.New("MaxValidator required one integer"))
+		return nil, errors.WithMessage(ErrInvalidArgument, "MaxValidator required one integer")
 	}
 	max := arg.Ints[0]
 	return func(arg interface{}) error {
@@ -215,14 +201,18 @@ func MaxValidator(arg ValidatorArgs) Validator {
 			}
 		}
 		return nil
-	}
+	}, nil
 }
 
-// MaxValidator return a Validator that check whether an integer is larger than the
+// MinValidator return a Validator that check whether an integer is larger than the
 // first arguments of the validator
 func MinValidator(arg ValidatorArgs) Validator {
+	return mustValidator(newMinValidator(arg))
+}
+
+func newMinValidator(arg ValidatorArgs) (Validator, error) {
 	if len(arg.Ints) < 1 {
-		panic(errors.New("MaxValidator required one integer"))
+		return nil, errors.WithMessage(ErrInvalidArgument, "MinValidator required one integer")
 	}
 	min := arg.Ints[0]
 	return func(arg interface{}) error {
@@ -236,15 +226,24 @@ func MinValidator(arg ValidatorArgs) Validator {
 			}
 		}
 		return nil
+	}, nil
+}
+
+// newNotEmptyValidator is NotEmptyValidator that rejects the types other than
+// string when compiling
+func newNotEmptyValidator(arg ValidatorArgs) (Validator, error) {
+	if err := stringType(arg, notEmptyValidatorName); err != nil {
+		return nil, err
 	}
+	return NotEmptyValidator(arg), nil
 }
 
 // EmptyValidator return a Validator that check whether a string is not empty
 func NotEmptyValidator(_ ValidatorArgs) Validator {
 	return func(arg interface{}) error {
-		s, ok := arg.(string)
-		if !ok {
-			return errors.New("not a string")
+		s, err := stringValue(arg)
+		if err != nil {
+			return err
 		}
 		if strings.TrimSpace(s) == "" {
 			return ValidatorError{
@@ -258,20 +257,24 @@ func NotEmptyValidator(_ ValidatorArgs) Validator {
 // RegexMatchValiator return a Validator that check whether a string match the
 // fisrt argument of the validator
 func RegexMatchValiator(v ValidatorArgs) Validator {
-	if v.Typ.Kind() != reflect.String {
-		panic("invalid type for regex validator")
+	return mustValidator(newRegexMatchValidator(v))
+}
+
+func newRegexMatchValidator(v ValidatorArgs) (Validator, error) {
+	if v.Typ == nil || v.Typ.Kind() != reflect.String {
+		return nil, errors.WithMessage(ErrInvalidArgument, "invalid type for regex validator")
 	}
 	if len(v.Strs) < 1 {
-		panic(errors.New("RegexValidator required one string"))
+		return nil, errors.WithMessage(ErrInvalidArgument, "RegexValidator required one string")
 	}
 	pat, err := regexp.Compile(v.Strs[0])
 	if err != nil {
-		panic(errors.WithMessage(err, "invalid regex pattern"))
+		return nil, errors.WithMessage(err, "invalid regex pattern")
 	}
 	return func(arg interface{}) error {
-		s, ok := arg.(string)
-		if !ok {
-			return errors.New("not a string")
+		s, err := stringValue(arg)
+		if err != nil {
+			return err
 		}
 		if !pat.MatchString(s) {
 			return ValidatorError{
@@ -279,24 +282,27 @@ func RegexMatchValiator(v ValidatorArgs) Validator {
 			}
 		}
 		return nil
-
-	}
+	}, nil
 }
 
 // LenMatchValiator return a Validator that check whether a string's length is
 // the same as the first argument of the validator
 func LenValidator(v ValidatorArgs) Validator {
-	if v.Typ.Kind() != reflect.String {
-		panic("invalid type for len validator")
+	return mustValidator(newLenValidator(v))
+}
+
+func newLenValidator(v ValidatorArgs) (Validator, error) {
+	if v.Typ == nil || v.Typ.Kind() != reflect.String {
+		return nil, errors.WithMessage(ErrInvalidArgument, "invalid type for len validator")
 	}
 	if len(v.Ints) < 1 {
-		panic("need an integer for len validator")
+		return nil, errors.WithMessage(ErrInvalidArgument, "need an integer for len validator")
 	}
 	l := v.Ints[0]
 	return func(arg interface{}) error {
-		s, ok := arg.(string)
-		if !ok {
-			return errors.New("not a string")
+		s, err := stringValue(arg)
+		if err != nil {
+			return err
 		}
 
 		if uint64(len(s)) != l {
@@ -305,15 +311,28 @@ func LenValidator(v ValidatorArgs) Validator {
 			}
 		}
 		return nil
+	}, nil
+}
+
+// mustValidator panics if err is not nil, it keeps the factories written before
+// ValidatorFactory panicking as they used to
+func mustValidator(vld Validator, err error) Validator {
+	if err != nil {
+		panic(err)
 	}
+	return vld
 }
 
 // StructValiator return a Validator that check whether a struct pointer of
 // struct value can pass the validation.
 func StructValidator(v ValidatorArgs) Validator {
-	vld, in := registeredStruct[v.Typ]
-	if !in {
-		panic(errors.WithMessage(ErrStructNotRegister, v.Typ.Name()))
+	e := v.engine
+	if e == nil {
+		e = defaultEngine
 	}
-	return vld
+	cs, err := e.structFor(e.load(), v.Typ, "")
+	if err != nil {
+		panic(errors.WithMessage(err, v.Typ.Name()))
+	}
+	return cs.validator(e)
 }
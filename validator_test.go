@@ -1,9 +1,15 @@
 package xvalidator
 
 import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ccbhj/xvalidator/internal"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,6 +44,26 @@ func TestRangeValidator(t *testing.T) {
 	assert.NotNil(t, fn("5"))
 }
 
+func TestNamedStringValidator(t *testing.T) {
+	type Status string
+	type Order struct {
+		Status Status `xvldt:"not_empty(), srange('paid', 'shipped')"`
+		Code   Status `xvldt:"len(3), regex('^[A-Z]+$')"`
+	}
+	e := New(WithAllErrors())
+	assert.Nil(t, e.RegisterStruct(&Order{}))
+	assert.Nil(t, e.ValidateStruct(&Order{Status: "paid", Code: "ABC"}))
+	assert.Equal(t, []string{
+		"Status: empty string", "Status: invalid value",
+		"Code: invalid string length", "Code: string not match pattern",
+	}, describe(e.ValidateStruct(&Order{Code: "ab"})))
+
+	assert.Nil(t, e.ValidateVar(Status("paid"), "srange('paid')"))
+	assert.Equal(t, []string{": invalid value"}, describe(e.ValidateVar(Status("new"), "srange('paid')")))
+	assert.Equal(t, []string{": empty string"}, describe(e.ValidateVar(Status(" "), "not_empty()")))
+	assert.Equal(t, []string{": out of range"}, describe(e.ValidateVar(Status("7"), "max(5)")))
+}
+
 func TestNewStructValidator(t *testing.T) {
 	type TestStruct struct {
 		A          int `xvldt:"irange(1, 99, 100), max(99)"`
@@ -138,3 +164,1299 @@ func TestParseArguments(t *testing.T) {
 		assert.Equal(t, exp.Strs, act.Strs)
 	}
 }
+
+func TestValidateStructAll(t *testing.T) {
+	errOdd := errors.New("odd")
+	type A struct {
+		I int `xvldt:"max(10), min(5), odd()"`
+	}
+	type B struct {
+		S string `xvldt:"not_empty(), len(3)"`
+		A A      `xvldt:"strct()"`
+		J int    `xvldt:"max(1)"`
+	}
+	odd := func(ValidatorArgs) Validator {
+		return func(arg interface{}) error {
+			if arg.(int)%2 != 0 {
+				return errOdd
+			}
+			return nil
+		}
+	}
+	e := New()
+	assert.Nil(t, e.RegisterValidator("odd", odd))
+	assert.Nil(t, e.RegisterStruct(A{}))
+	assert.Nil(t, e.RegisterStruct(B{}))
+
+	var b = B{S: "", A: A{I: 11}, J: 2}
+	// stop at the first error by default
+	err := e.ValidateStruct(b)
+	assert.Equal(t, []string{"S: empty string"}, describe(err))
+
+	err = e.ValidateStructAll(b)
+	var errs ValidationErrors
+	if !assert.True(t, errors.As(err, &errs)) {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{
+		"S: empty string",
+		"S: invalid string length",
+		"A.I: out of range",
+		"A.I: odd",
+		"J: out of range",
+	}, describe(errs))
+	assert.Equal(t, "I", errs[3].FieldName)
+	assert.Equal(t, errOdd, errs[3].Err)
+	assert.True(t, errors.Is(err, errOdd))
+	var ve ValidatorError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, "S", ve.FieldName)
+	// the same without the multi-error Unwrap of go1.20
+	ve = ValidatorError{}
+	assert.True(t, errs.Is(errOdd))
+	assert.False(t, errs.Is(ErrInvalidStruct))
+	assert.True(t, errs.As(&ve))
+	assert.Equal(t, "S", ve.FieldName)
+
+	assert.Nil(t, e.ValidateStructAll(B{S: "abc", A: A{I: 6}}))
+
+	// per engine mode with a cap
+	e = New(WithAllErrors(), WithMaxErrors(2))
+	assert.Nil(t, e.RegisterValidator("odd", odd))
+	assert.Nil(t, e.RegisterStruct(A{}))
+	assert.Nil(t, e.RegisterStruct(B{}))
+	err = e.ValidateStruct(b)
+	assert.IsType(t, ValidationErrors{}, err)
+	assert.Equal(t, []string{"S: empty string", "S: invalid string length"}, describe(err))
+	err = e.NewStructValidator(A{})(A{I: 4})
+	assert.IsType(t, ValidationErrors{}, err)
+	assert.Equal(t, []string{"I: out of range"}, describe(err))
+}
+
+func TestCompileStructTagError(t *testing.T) {
+	type Unknown struct {
+		I int `xvldt:"max(1), mx(10)"`
+	}
+	type BadArgs struct {
+		I int `xvldt:"max(1), irange(1, 2x)"`
+	}
+	type UnknownConst struct {
+		S string `xvldt:"srange('a', NOT_FOUND)"`
+	}
+	type Mismatch struct {
+		I int `xvldt:"len(3)"`
+	}
+	type Unclosed struct {
+		S string `xvldt:"regex('(a|b)'), max(1"`
+	}
+	type NotRegistered struct {
+		U Unknown `xvldt:"strct()"`
+	}
+	type Failing struct {
+		S string `xvldt:"not_empty(), fail()"`
+	}
+	type Panicking struct {
+		S string `xvldt:"panic()"`
+	}
+
+	errFail := errors.New("fail")
+	e := New()
+	assert.Nil(t, e.RegisterValidatorFactory("fail", func(ValidatorArgs) (Validator, error) {
+		return nil, errFail
+	}))
+	assert.Nil(t, e.RegisterValidator("panic", func(ValidatorArgs) Validator {
+		panic("bad validator")
+	}))
+
+	testCases := []struct {
+		strct     interface{}
+		field     string
+		validator string
+		offset    int
+		err       error
+	}{
+		{Unknown{}, "I", "mx", 8, ErrUnknownValidator},
+		{&BadArgs{}, "I", "irange", 19, ErrInvalidValidatorSyntax},
+		{UnknownConst{}, "S", "srange", 12, ErrUnknownConst},
+		{Mismatch{}, "I", "len", 0, ErrInvalidArgument},
+		{Unclosed{}, "S", "", 19, ErrInvalidValidatorSyntax},
+		{NotRegistered{}, "U", "strct", 0, ErrStructNotRegister},
+		{Failing{}, "S", "fail", 13, errFail},
+	}
+	for _, tc := range testCases {
+		vld, err := e.CompileStruct(tc.strct)
+		assert.Nil(t, vld)
+		var te TagError
+		if !assert.True(t, errors.As(err, &te), "%T: %v", tc.strct, err) {
+			continue
+		}
+		assert.Equal(t, internal.TypeIndirect(reflect.TypeOf(tc.strct)), te.Struct)
+		assert.Equal(t, tc.field, te.Field)
+		assert.Equal(t, tc.validator, te.Validator)
+		assert.Equal(t, tc.offset, te.Offset, "%T: %v", tc.strct, err)
+		assert.True(t, errors.Is(err, tc.err), "%T: %v", tc.strct, err)
+		assert.Equal(t, err, e.RegisterStruct(tc.strct))
+	}
+
+	err := e.RegisterStruct(Panicking{})
+	var te TagError
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, "bad validator", te.Err.Error())
+
+	_, err = e.CompileStruct(1)
+	assert.True(t, errors.Is(err, ErrInvalidValidatorArgument))
+	_, err = e.CompileStruct(nil)
+	assert.True(t, errors.Is(err, ErrInvalidValidatorArgument))
+
+	assert.NotNil(t, TryRegisterStruct(Mismatch{}))
+	assert.Panics(t, func() { RegisterStruct(Mismatch{}) })
+	assert.Panics(t, func() { NewStructValidator(Mismatch{}) })
+}
+
+func TestParseCalls(t *testing.T) {
+	calls, err := internal.ParseCalls(` max(10),regex('(a|b\')'), each(min(1), max(2)) `)
+	assert.Nil(t, err)
+	assert.Equal(t, []internal.Call{
+		{Name: "max", Args: "10", Offset: 1, ArgsOffset: 5},
+		{Name: "regex", Args: `'(a|b\')'`, Offset: 9, ArgsOffset: 15},
+		{Name: "each", Args: "min(1), max(2)", Offset: 27, ArgsOffset: 32},
+	}, calls)
+
+	for s, off := range map[string]int{
+		"max":          3,
+		"max (1)":      3,
+		"1max()":       0,
+		"max(1))":      6,
+		"max((1)":      3,
+		"max(')":       3,
+		"max(1) | x()": 7,
+	} {
+		_, err := internal.ParseCalls(s)
+		var se *internal.SyntaxError
+		if assert.True(t, errors.As(err, &se), s) {
+			assert.Equal(t, off, se.Offset, s)
+		}
+	}
+}
+
+func TestRecursiveStruct(t *testing.T) {
+	type Node struct {
+		Name     string  `xvldt:"not_empty()"`
+		Parent   *Node   `xvldt:"strct()"`
+		Children []*Node `xvldt:"strct()"`
+	}
+	e := New()
+	assert.Nil(t, e.RegisterStruct(Node{}))
+
+	root := &Node{Name: "root"}
+	child := &Node{Name: "child", Parent: root}
+	root.Parent = root
+	root.Children = []*Node{child, {Name: "leaf", Parent: root}}
+	// pointer cycles are validated once
+	assert.Nil(t, e.ValidateStruct(root))
+	root.Children[1].Name = " "
+	assert.Equal(t, []string{"Children[1].Name: empty string"}, describe(e.ValidateStruct(root)))
+	assert.Equal(t, []string{"Parent.Children[1].Name: empty string"}, describe(e.ValidateStruct(child)))
+
+	// too deep
+	e = New(WithMaxDepth(3))
+	assert.Nil(t, e.RegisterStruct(Node{}))
+	n := &Node{Name: "0"}
+	for i := 1; i < 3; i++ {
+		n = &Node{Name: strconv.Itoa(i), Children: []*Node{n}}
+	}
+	assert.Nil(t, e.ValidateStruct(n))
+	n = &Node{Name: "3", Children: []*Node{n}}
+	assert.Equal(t, ErrMaxDepth, e.ValidateStruct(n))
+}
+
+type testCategory struct {
+	Name   string               `xvldt:"not_empty()"`
+	Groups []*testCategoryGroup `xvldt:"strct()"`
+}
+
+type testCategoryGroup struct {
+	Title string        `xvldt:"not_empty()"`
+	Owner *testCategory `xvldt:"strct()"`
+}
+
+func TestMutuallyRecursiveStruct(t *testing.T) {
+	cat := &testCategory{Name: "c"}
+	cat.Groups = []*testCategoryGroup{{Title: "g", Owner: cat}}
+
+	// a cycle cannot be registered one by one
+	e := New()
+	assert.True(t, errors.Is(e.RegisterStruct(testCategory{}), ErrStructNotRegister))
+	assert.True(t, errors.Is(e.RegisterStruct(testCategoryGroup{}), ErrStructNotRegister))
+	// but can be registered as a group
+	assert.Nil(t, e.RegisterStructs(testCategory{}, testCategoryGroup{}))
+	assert.Nil(t, e.ValidateStruct(cat))
+	assert.Nil(t, e.ValidateStruct(cat.Groups[0]))
+
+	// or compiled on demand
+	e = New(WithAutoCompile())
+	assert.Nil(t, e.ValidateStruct(cat))
+	cat.Groups[0].Title = ""
+	assert.Equal(t, []string{"Groups[0].Title: empty string"}, describe(e.ValidateStruct(cat)))
+	assert.Equal(t, []string{"Title: empty string"}, describe(e.ValidateStruct(cat.Groups[0])))
+}
+
+// describe return every ValidatorError in err as "path: reason"
+func describe(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var es ValidationErrors
+	if !errors.As(err, &es) {
+		var e ValidatorError
+		if !errors.As(err, &e) {
+			return []string{err.Error()}
+		}
+		es = ValidationErrors{e}
+	}
+	ret := make([]string, 0, len(es))
+	for _, e := range es {
+		ret = append(ret, e.Path.String()+": "+e.Reason)
+	}
+	return ret
+}
+
+func TestPath(t *testing.T) {
+	type Item struct {
+		SKU string `xvldt:"len(4)"`
+	}
+	type Order struct {
+		Items []Item `xvldt:"strct()"`
+	}
+	type Req struct {
+		Order *Order `xvldt:"strct()"`
+	}
+	e := New(WithAutoCompile())
+	req := Req{Order: &Order{Items: []Item{{SKU: "abcd"}, {SKU: "abcd"}, {SKU: "abcd"}, {SKU: "x"}}}}
+	err := e.ValidateStruct(req)
+	var ve ValidatorError
+	if !assert.True(t, errors.As(err, &ve)) {
+		t.FailNow()
+	}
+	assert.Equal(t, "SKU", ve.FieldName)
+	assert.Equal(t, Path{fieldSegment("Order"), fieldSegment("Items"), indexSegment(3), fieldSegment("SKU")}, ve.Path)
+	assert.Equal(t, "Order.Items[3].SKU", ve.Path.String())
+	assert.Equal(t, "/Order/Items/3/SKU", ve.Path.JSONPointer())
+	assert.Equal(t, "validate fail for field Order.Items[3].SKU: invalid string length", ve.Error())
+
+	p := Path{fieldSegment("Labels"), keySegment("a/b~c"), keySegment(1), indexSegment(0)}
+	assert.Equal(t, `Labels["a/b~c"][1][0]`, p.String())
+	assert.Equal(t, "/Labels/a~1b~0c/1/0", p.JSONPointer())
+	assert.Equal(t, "", Path{}.JSONPointer())
+}
+
+type BaseModel struct {
+	ID      string `xvldt:"len(4)"`
+	Version int    `xvldt:"min(1)"`
+}
+
+type Audit struct {
+	By string `xvldt:"not_empty()"`
+}
+
+type Timestamps struct {
+	Version int `xvldt:"max(0)"` // hidden by BaseModel.Version
+}
+
+func TestEmbeddedStruct(t *testing.T) {
+	type Doc struct {
+		BaseModel
+		*Audit
+		Name string `xvldt:"not_empty()"`
+		ID   string `xvldt:"len(2)"` // hides BaseModel.ID
+	}
+	type Nested struct {
+		Doc
+	}
+	type OptOut struct {
+		BaseModel `xvldt:"-"`
+		Name      string `xvldt:"not_empty()"`
+	}
+	type Explicit struct {
+		BaseModel `xvldt:"strct()"`
+	}
+	type Ambiguous struct {
+		BaseModel
+		Timestamps
+	}
+
+	e := New(WithAutoCompile())
+	doc := Doc{BaseModel: BaseModel{ID: "abcd", Version: 1}, Name: "n", ID: "ab"}
+	assert.Nil(t, e.ValidateStruct(doc))
+	doc.Version = 0
+	assert.Equal(t, []string{"Version: out of range"}, describe(e.ValidateStruct(doc)))
+	assert.Equal(t, []string{"Version: out of range"}, describe(e.ValidateStruct(Nested{Doc: doc})))
+	doc.Audit = &Audit{}
+	doc.BaseModel.ID = "x"
+	assert.Equal(t, []string{"Version: out of range", "By: empty string"},
+		describe(e.ValidateStructAll(doc)))
+
+	assert.Nil(t, e.ValidateStruct(OptOut{Name: "n"}))
+	assert.Equal(t, []string{"BaseModel.Version: out of range"},
+		describe(e.ValidateStruct(Explicit{BaseModel: BaseModel{ID: "abcd"}})))
+	// Version is ambiguous so it is not promoted
+	assert.Equal(t, []string{"ID: invalid string length"}, describe(e.ValidateStructAll(Ambiguous{})))
+}
+
+func TestEachValidator(t *testing.T) {
+	type Item struct {
+		SKU string `xvldt:"len(4)"`
+	}
+	type S struct {
+		Tags  []string  `xvldt:"each(not_empty(), regex('^[a-z]+$'))"`
+		Ports [2]int    `xvldt:"each(min(1), max(100))"`
+		IDs   *[]uint32 `xvldt:"each(irange(1, 2, 3))"`
+		Items []*Item   `xvldt:"each()"`
+	}
+	e := New(WithAutoCompile())
+	s := S{Tags: []string{"a", "b"}, Ports: [2]int{1, 100}, Items: []*Item{{SKU: "abcd"}, nil}}
+	assert.Nil(t, e.ValidateStruct(s))
+
+	s.Tags = append(s.Tags, "C", "")
+	s.Ports[1] = 101
+	ids := []uint32{1, 4}
+	s.IDs = &ids
+	s.Items = append(s.Items, &Item{SKU: "x"})
+	assert.Equal(t, []string{"Tags[2]: string not match pattern"}, describe(e.ValidateStruct(s)))
+	assert.Equal(t, []string{
+		"Tags[2]: string not match pattern",
+		"Tags[3]: empty string",
+		"Tags[3]: string not match pattern",
+		"Ports[1]: out of range",
+		"IDs[1]: invalid value",
+		"Items[2].SKU: invalid string length",
+	}, describe(e.ValidateStructAll(s)))
+
+	type NotList struct {
+		S string `xvldt:"each(not_empty())"`
+	}
+	type BadElem struct {
+		S []int `xvldt:"each(max(1), len(1))"`
+	}
+	var te TagError
+	assert.True(t, errors.As(e.RegisterStruct(NotList{}), &te))
+	assert.Equal(t, "each", te.Validator)
+	assert.True(t, errors.As(e.RegisterStruct(BadElem{}), &te))
+	assert.Equal(t, "len", te.Validator)
+	assert.Equal(t, 13, te.Offset)
+
+	assert.True(t, errors.Is(e.RegisterValidator("each", NotEmptyValidator), ErrInvalidName))
+
+	// the elements of untagged structs need no registration
+	type Times struct {
+		At []time.Time `xvldt:"each(required())"`
+	}
+	e = New()
+	assert.Nil(t, e.RegisterStruct(Times{}))
+	assert.Nil(t, e.ValidateStruct(Times{At: []time.Time{time.Now()}}))
+	assert.Equal(t, []string{"At[0]: required"}, describe(e.ValidateStruct(Times{At: []time.Time{{}}})))
+	type Items struct {
+		Items []Item `xvldt:"each()"`
+	}
+	assert.True(t, errors.Is(e.RegisterStruct(Items{}), ErrStructNotRegister))
+}
+
+func TestMapValidator(t *testing.T) {
+	type Attribute struct {
+		Value string `xvldt:"not_empty()"`
+	}
+	type S struct {
+		Labels map[string]string     `xvldt:"keys(regex('^[a-z]+$')), values(not_empty())"`
+		Attrs  map[string]*Attribute `xvldt:"values()"`
+		Codes  map[int][]string      `xvldt:"keys(max(10)), values(each(len(2)))"`
+	}
+	e := New(WithAutoCompile())
+	s := S{
+		Labels: map[string]string{"env": "prod", "team": "a"},
+		Attrs:  map[string]*Attribute{"color": {Value: "red"}},
+		Codes:  map[int][]string{1: {"ab"}},
+	}
+	assert.Nil(t, e.ValidateStruct(s))
+	assert.Nil(t, e.ValidateStruct(S{}))
+
+	s.Labels["Bad"] = ""
+	s.Labels["zone"] = " "
+	s.Attrs["size"] = &Attribute{}
+	s.Codes[11] = []string{"ab", "abc"}
+	// errors are reported in the order of the sorted keys
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, []string{
+			`Labels["Bad"]: string not match pattern`,
+			`Labels["Bad"]: empty string`,
+			`Labels["zone"]: empty string`,
+			`Attrs["size"].Value: empty string`,
+			`Codes[11]: out of range`,
+			`Codes[11][1]: invalid string length`,
+		}, describe(e.ValidateStructAll(s)))
+	}
+	var ve ValidatorError
+	assert.True(t, errors.As(e.ValidateStruct(s), &ve))
+	assert.Equal(t, "/Labels/Bad", ve.Path.JSONPointer())
+
+	type NotMap struct {
+		S []string `xvldt:"keys(not_empty())"`
+	}
+	var te TagError
+	assert.True(t, errors.As(e.RegisterStruct(NotMap{}), &te))
+	assert.Equal(t, "keys", te.Validator)
+
+	// the values of untagged structs need no registration
+	type Times struct {
+		At map[string]time.Time `xvldt:"values(required())"`
+	}
+	e = New()
+	assert.Nil(t, e.RegisterStruct(Times{}))
+	assert.Equal(t, []string{`At["a"]: required`}, describe(e.ValidateStruct(Times{At: map[string]time.Time{"a": {}}})))
+	type Attrs struct {
+		Attrs map[string]Attribute `xvldt:"values()"`
+	}
+	assert.True(t, errors.Is(e.RegisterStruct(Attrs{}), ErrStructNotRegister))
+}
+
+func TestNilAndOptionalFields(t *testing.T) {
+	type Inner struct {
+		I int `xvldt:"max(1)"`
+	}
+	type S struct {
+		Name  *string          `xvldt:"not_empty()"`
+		Opt   *string          `xvldt:"omitempty(), len(2)"`
+		Count int              `xvldt:"omitempty(), min(10)"`
+		Req   *int             `xvldt:"required(), max(5)"`
+		List  []string         `xvldt:"required(), each(not_empty())"`
+		Any   interface{}      `xvldt:"omitempty(), max(3)"`
+		Inner *Inner           `xvldt:"strct()"`
+		Map   map[string]*bool `xvldt:"values(required())"`
+	}
+	e := New(WithAutoCompile())
+	name, opt, zero, yes := "n", "op", 0, true
+	ok := S{Name: &name, Opt: &opt, Req: &zero, List: []string{"a"}}
+	assert.Nil(t, e.ValidateStruct(ok))
+
+	// by default a nil pointer fails the validators but nested structs,
+	// elements, keys and values of a nil are not validated
+	assert.Equal(t, []string{
+		"Name: nil value",
+		"Req: required",
+		"Req: nil value",
+		"List: required",
+	}, describe(e.ValidateStructAll(S{})))
+
+	bad := ok
+	bad.Opt = new(string)
+	bad.Count = 1
+	bad.Any = 4
+	bad.Inner = &Inner{I: 2}
+	bad.Map = map[string]*bool{"a": &yes, "b": nil}
+	bad.List = []string{}
+	assert.Equal(t, []string{
+		"Opt: invalid string length",
+		"Count: out of range",
+		"Any: out of range",
+		"Inner.I: out of range",
+		`Map["b"]: required`,
+	}, describe(e.ValidateStructAll(bad)))
+
+	type BadArgs struct {
+		I int `xvldt:"required(1)"`
+	}
+	var te TagError
+	assert.True(t, errors.As(e.RegisterStruct(BadArgs{}), &te))
+	assert.Equal(t, 9, te.Offset)
+	assert.True(t, errors.Is(e.RegisterValidator("required", NotEmptyValidator), ErrInvalidName))
+}
+
+func TestValidateStructCtx(t *testing.T) {
+	type tenantKey struct{}
+	type Inner struct {
+		I int `xvldt:"max(1)"`
+	}
+	type S struct {
+		Plan  string  `xvldt:"plan()"`
+		Slow  string  `xvldt:"slow()"`
+		Inner []Inner `xvldt:"each()"`
+	}
+	var cancel context.CancelFunc
+	newEngine := func(opts ...Option) *Engine {
+		e := New(append(opts, WithAutoCompile())...)
+		assert.Nil(t, e.RegisterValidatorCtx("plan", func(ValidatorArgs) (ValidatorCtx, error) {
+			return func(ctx context.Context, v interface{}) error {
+				tenant, _ := ctx.Value(tenantKey{}).(string)
+				if tenant != "paid" && v.(string) == "pro" {
+					return ValidatorError{Reason: "plan not available"}
+				}
+				return nil
+			}, nil
+		}))
+		assert.Nil(t, e.RegisterValidatorCtx("slow", func(ValidatorArgs) (ValidatorCtx, error) {
+			return func(ctx context.Context, v interface{}) error {
+				switch v.(string) {
+				case "cancel":
+					cancel()
+				case "wait":
+					<-ctx.Done()
+					return errors.WithMessage(ctx.Err(), "lookup")
+				}
+				return nil
+			}, nil
+		}))
+		return e
+	}
+
+	e := newEngine()
+	paid := context.WithValue(context.Background(), tenantKey{}, "paid")
+	assert.Nil(t, e.ValidateStructCtx(paid, S{Plan: "pro"}))
+	assert.Equal(t, []string{"Plan: plan not available"},
+		describe(e.ValidateStructCtx(context.Background(), S{Plan: "pro"})))
+	// validators see a background context without ValidateStructCtx
+	assert.NotNil(t, e.ValidateStruct(S{Plan: "pro"}))
+
+	// canceled between fields
+	s := S{Slow: "cancel", Inner: []Inner{{I: 2}}}
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	assert.Equal(t, context.Canceled, e.ValidateStructCtx(ctx, s))
+	ctx, cancel = context.WithCancel(context.Background())
+	assert.Equal(t, context.Canceled, newEngine(WithAllErrors()).ValidateStructCtx(ctx, s))
+
+	// deadline reached inside a validator is not collected as a failure
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, newEngine(WithAllErrors()).ValidateStructCtx(ctx, S{Slow: "wait"}))
+}
+
+func TestCrossFieldValidator(t *testing.T) {
+	type Range struct {
+		Start time.Time
+		End   *time.Time `xvldt:"gtfield(Start)"`
+	}
+	type Signup struct {
+		Password        string `xvldt:"not_empty()"`
+		ConfirmPassword string `xvldt:"eqfield(Password)"`
+		Username        string `xvldt:"nefield('Password')"`
+		Min             int
+		Max             uint8 `xvldt:"gtefield(Min)"`
+		Limit           int64 `xvldt:"ltefield(Quota.Max)"`
+		Quota           *struct{ Max float64 }
+		Period          Range `xvldt:"strct()"`
+		Retries         []int `xvldt:"each(ltfield(Min))"`
+	}
+	e := New(WithAutoCompile())
+	now := time.Now()
+	later := now.Add(time.Hour)
+	s := Signup{
+		Password: "p", ConfirmPassword: "p", Username: "u",
+		Min: 1, Max: 1, Limit: 2,
+		Quota:   &struct{ Max float64 }{Max: 2.5},
+		Period:  Range{Start: now, End: &later},
+		Retries: []int{0},
+	}
+	assert.Nil(t, e.ValidateStruct(s))
+
+	s.ConfirmPassword = "q"
+	s.Username = "p"
+	s.Max = 0
+	s.Limit = 3
+	s.Period.End = &now
+	s.Retries = []int{0, 1}
+	assert.Equal(t, []string{
+		"ConfirmPassword: must be equal to Password",
+		"Username: must not be equal to Password",
+		"Max: must be greater than or equal to Min",
+		"Limit: must be less than or equal to Quota.Max",
+		"Period.End: must be greater than Start",
+		"Retries[1]: must be less than Min",
+	}, describe(e.ValidateStructAll(s)))
+
+	s.Quota = nil
+	s.Period.End = nil
+	assert.Equal(t, []string{
+		"ConfirmPassword: must be equal to Password",
+		"Username: must not be equal to Password",
+		"Max: must be greater than or equal to Min",
+		"Limit: nil value",
+		"Period.End: nil value",
+		"Retries[1]: must be less than Min",
+	}, describe(e.ValidateStructAll(s)))
+
+	// the dynamic values of interfaces may not be comparable
+	type Holder struct{ V interface{} }
+	type Dynamic struct {
+		A interface{} `xvldt:"eqfield(B)"`
+		B interface{}
+		C Holder `xvldt:"nefield(D)"`
+		D Holder
+	}
+	e = New(WithAllErrors(), WithAutoCompile())
+	assert.Nil(t, e.ValidateStruct(Dynamic{A: 1, B: 1, C: Holder{1}, D: Holder{2}}))
+	assert.Equal(t, []string{
+		"A: must be equal to B",
+		"C: must not be equal to D",
+	}, describe(e.ValidateStruct(Dynamic{A: 1, B: "1", C: Holder{1}, D: Holder{1}})))
+	assert.Equal(t, []string{
+		"A: not comparable",
+		"C: not comparable",
+	}, describe(e.ValidateStruct(Dynamic{A: []int{1}, B: []int{1}, C: Holder{[]int{1}}, D: Holder{map[int]int{}}})))
+
+	type Unknown struct {
+		A string `xvldt:"eqfield(B)"`
+	}
+	type UnknownNested struct {
+		A string `xvldt:"eqfield(B.C)"`
+		B struct{ D string }
+	}
+	type Mismatch struct {
+		A string `xvldt:"gtfield(B)"`
+		B int
+	}
+	type Unordered struct {
+		A bool `xvldt:"ltfield(B)"`
+		B bool
+	}
+	type TimeMismatch struct {
+		A int64 `xvldt:"ltfield(B.Start)"`
+		B Range
+	}
+	for _, strct := range []interface{}{Unknown{}, UnknownNested{}, Mismatch{}, Unordered{}, TimeMismatch{}} {
+		_, err := e.CompileStruct(strct)
+		var te TagError
+		assert.True(t, errors.As(err, &te), "%T", strct)
+	}
+	_, err := e.CompileStruct(Unknown{})
+	assert.True(t, errors.Is(err, ErrUnknownField))
+	_, err = e.CompileStruct(UnknownNested{})
+	assert.True(t, errors.Is(err, ErrUnknownField))
+}
+
+func TestConditionalValidator(t *testing.T) {
+	type Address struct {
+		City string `xvldt:"not_empty()"`
+	}
+	type Order struct {
+		DeliveryMethod  string
+		ShippingAddress *Address `xvldt:"required_if(DeliveryMethod, 'ship'), strct()"`
+		PickupStore     string   `xvldt:"required_unless(DeliveryMethod, 'ship', 'digital')"`
+		Email           string
+		Phone           string `xvldt:"required_without(Email)"`
+		PhoneRegion     string `xvldt:"required_with(Phone), when(DeliveryMethod, 'ship', len(2))"`
+		Priority        int
+		Note            string `xvldt:"when(Priority, 1, not_empty(), len(3))"`
+	}
+	e := New(WithAutoCompile())
+	o := Order{DeliveryMethod: "ship", ShippingAddress: &Address{City: "c"}, Email: "e", PhoneRegion: "ab"}
+	assert.Nil(t, e.ValidateStruct(o))
+
+	o.ShippingAddress.City = ""
+	assert.Equal(t, []string{"ShippingAddress.City: empty string"}, describe(e.ValidateStructAll(o)))
+
+	o = Order{DeliveryMethod: "ship", Phone: "p", PhoneRegion: "abc", Priority: 1}
+	assert.Equal(t, []string{
+		"ShippingAddress: required",
+		"PhoneRegion: invalid string length",
+		"Note: empty string",
+		"Note: invalid string length",
+	}, describe(e.ValidateStructAll(o)))
+
+	o = Order{DeliveryMethod: "pickup", PhoneRegion: "abc"}
+	assert.Equal(t, []string{
+		"PickupStore: required",
+		"Phone: required",
+	}, describe(e.ValidateStructAll(o)))
+
+	o = Order{DeliveryMethod: "pickup", PickupStore: "s", Phone: "p"}
+	assert.Equal(t, []string{"PhoneRegion: required"}, describe(e.ValidateStructAll(o)))
+
+	type Unknown struct {
+		A string `xvldt:"required_if(B, 'x')"`
+	}
+	type Uncomparable struct {
+		A string `xvldt:"required_if(B, 'x')"`
+		B []string
+	}
+	type NoValue struct {
+		A string `xvldt:"required_if(B)"`
+		B string
+	}
+	type NoValidator struct {
+		A string `xvldt:"when(B, 'x')"`
+		B string
+	}
+	type BadNested struct {
+		A string `xvldt:"when(B, 'x', mx(1))"`
+		B string
+	}
+	for _, strct := range []interface{}{Unknown{}, Uncomparable{}, NoValue{}, NoValidator{}, BadNested{}} {
+		_, err := e.CompileStruct(strct)
+		var te TagError
+		assert.True(t, errors.As(err, &te), "%T", strct)
+	}
+	_, err := e.CompileStruct(Unknown{})
+	assert.True(t, errors.Is(err, ErrUnknownField))
+	_, err = e.CompileStruct(BadNested{})
+	assert.True(t, errors.Is(err, ErrUnknownValidator))
+	var te TagError
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, 13, te.Offset)
+}
+
+func TestCombinatorValidator(t *testing.T) {
+	type Account struct {
+		ID       string   `xvldt:"or(len(36), regex('^[0-9]+$'))"`
+		Username string   `xvldt:"not_empty(), not(srange('admin', 'root'))"`
+		Code     string   `xvldt:"or(all(len(4), regex('^[A-Z]+$')), srange('none'))"`
+		Tags     []string `xvldt:"each(not(regex('^_')))"`
+	}
+	e := New(WithAutoCompile())
+	a := Account{ID: "42", Username: "alice", Code: "ABCD", Tags: []string{"a"}}
+	assert.Nil(t, e.ValidateStruct(a))
+	a.ID = "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+	a.Code = "none"
+	assert.Nil(t, e.ValidateStruct(a))
+
+	a = Account{ID: "id", Username: "root", Code: "abcd", Tags: []string{"a", "_b"}}
+	assert.Equal(t, []string{
+		"ID: none of the alternatives passed: len: invalid string length; regex: string not match pattern",
+		"Username: must not pass srange('admin', 'root')",
+		"Code: none of the alternatives passed: all: string not match pattern; srange: invalid value",
+		"Tags[1]: must not pass regex('^_')",
+	}, describe(e.ValidateStructAll(a)))
+
+	err := e.ValidateStruct(a)
+	var es ValidationErrors
+	assert.True(t, errors.As(err, &es))
+	assert.Equal(t, 2, len(es))
+
+	e = New(WithAutoCompile(), WithMaxErrors(1))
+	assert.Equal(t, []string{
+		"ID: none of the alternatives passed: len: invalid string length; regex: string not match pattern",
+	}, describe(e.ValidateStructAll(a)))
+
+	type Empty struct {
+		A string `xvldt:"or()"`
+	}
+	type BadBranch struct {
+		A string `xvldt:"not(mx(1))"`
+	}
+	for _, strct := range []interface{}{Empty{}, BadBranch{}} {
+		_, err := e.CompileStruct(strct)
+		var te TagError
+		assert.True(t, errors.As(err, &te), "%T", strct)
+	}
+}
+
+type hookItem struct {
+	SKU string `xvldt:"not_empty()"`
+	Qty int
+}
+
+func (i hookItem) XValidate() error {
+	if i.Qty <= 0 {
+		return FieldError("Qty", errors.New("must be positive"))
+	}
+	return nil
+}
+
+type hookOrder struct {
+	Items []hookItem `xvldt:"each()"`
+	Start int
+	End   int
+}
+
+type tenantKey struct{}
+
+func (o *hookOrder) XValidateCtx(ctx context.Context) error {
+	var errs ValidationErrors
+	if o.End < o.Start {
+		errs = append(errs, FieldError("End", ValidatorError{Reason: "before start"}).(ValidatorError))
+	}
+	if ctx.Value(tenantKey{}) == nil {
+		errs = append(errs, ValidatorError{Reason: "no tenant"})
+	}
+	if len(o.Items) > 1 && o.Items[0].SKU == o.Items[1].SKU {
+		errs = append(errs, FieldError("Items[1].SKU", errors.New("duplicated")).(ValidatorError))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func TestValidatable(t *testing.T) {
+	e := New(WithAutoCompile())
+	ctx := context.WithValue(context.Background(), tenantKey{}, "t")
+	o := hookOrder{Items: []hookItem{{SKU: "a", Qty: 1}}, Start: 1, End: 2}
+	assert.Nil(t, e.ValidateStructCtx(ctx, o))
+	assert.Nil(t, e.ValidateStructCtx(ctx, &o))
+
+	o = hookOrder{Items: []hookItem{{SKU: "a", Qty: 1}, {SKU: "a"}, {}}, Start: 2, End: 1}
+	assert.Equal(t, []string{
+		"Items[1].Qty: must be positive",
+		"Items[2].SKU: empty string",
+		"Items[2].Qty: must be positive",
+		"End: before start",
+		": no tenant",
+		"Items[1].SKU: duplicated",
+	}, describe(e.ValidateStructAll(&o)))
+
+	// in fail-fast mode the hook runs only if all the fields pass
+	err := e.ValidateStruct(o)
+	assert.Equal(t, []string{"Items[1].Qty: must be positive"}, describe(err))
+	var ve ValidatorError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, "Qty", ve.FieldName)
+
+	o.Items = nil
+	assert.Equal(t, []string{"End: before start", ": no tenant"}, describe(e.ValidateStruct(o)))
+
+	e = New(WithAutoCompile(), WithMaxErrors(2))
+	o.Items = []hookItem{{}}
+	assert.Equal(t, []string{
+		"Items[0].SKU: empty string",
+		"Items[0].Qty: must be positive",
+	}, describe(e.ValidateStructAll(o)))
+
+	assert.Nil(t, FieldError("A", nil))
+	err = FieldError("A.B", ValidationErrors{{Reason: "x"}, {Reason: "y", Path: Path{fieldSegment("C")}}})
+	assert.Equal(t, []string{"A.B: x", "A.B.C: y"}, describe(err))
+
+	// Validate is not a hook, so that it can delegate to the Engine
+	assert.Nil(t, (&selfValidated{Name: "a"}).Validate())
+	assert.Equal(t, []string{"Name: empty string"}, describe((&selfValidated{}).Validate()))
+
+	// XValidateCtx takes precedence even with a pointer receiver
+	assert.Equal(t, []string{": ctx"}, describe(e.ValidateStruct(mixedHook{})))
+	assert.Equal(t, []string{": ctx"}, describe(e.ValidateStruct(&mixedHook{})))
+	assert.Equal(t, []string{": ctx"}, describe(CallHook(&mixedHook{})))
+
+	assert.Equal(t, ErrInvalidStruct, CallHook(hookOrder{}))
+	assert.Equal(t, []string{": no tenant"}, describe(CallHook(&hookOrder{})))
+	assert.Nil(t, CallHook(&hookItem{Qty: 1}))
+	assert.Nil(t, Locate("A", nil))
+	plain := errors.New("x")
+	assert.Equal(t, plain, Locate("A", plain))
+	err = Locate("A[1]", FieldError("B", ValidatorError{Reason: "x"}))
+	assert.Equal(t, []string{"A[1].B: x"}, describe(err))
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, "B", ve.FieldName)
+}
+
+// selfValidated delegates its Validate to the Engine
+type selfValidated struct {
+	Name string `xvldt:"not_empty()"`
+}
+
+var selfEngine = New(WithAutoCompile())
+
+func (s *selfValidated) Validate() error { return selfEngine.ValidateStruct(s) }
+
+// mixedHook has XValidate on the value and XValidateCtx on the pointer
+type mixedHook struct{}
+
+func (mixedHook) XValidate() error { return ValidatorError{Reason: "plain"} }
+
+func (*mixedHook) XValidateCtx(context.Context) error { return ValidatorError{Reason: "ctx"} }
+
+func TestValidationGroup(t *testing.T) {
+	type Owner struct {
+		Name string `xvldt:"update: not_empty()"`
+	}
+	type Item struct {
+		ID    string `xvldt:"create: len(0); update: not_empty(), len(36)"`
+		Name  string `xvldt:"not_empty()"`
+		Owner Owner  `xvldt:"strct()"`
+		Note  string `xvldt:"omitempty(); create: len(3)"`
+	}
+	e := New()
+	assert.Nil(t, e.RegisterStructs(Item{}, Owner{}))
+
+	i := Item{Name: "n"}
+	assert.Nil(t, e.ValidateStruct(i))
+	assert.Nil(t, e.ValidateStructGroup(i, "create"))
+	assert.Nil(t, e.ValidateStructGroup(&i, ""))
+	assert.Equal(t, []string{"ID: empty string"}, describe(e.ValidateStructGroup(i, "update")))
+
+	i = Item{ID: "id", Note: "note"}
+	e = New(WithAllErrors(), WithAutoCompile())
+	assert.Equal(t, []string{"Name: empty string"}, describe(e.ValidateStruct(i)))
+	assert.Equal(t, []string{
+		"ID: invalid string length",
+		"Name: empty string",
+		"Note: invalid string length",
+	}, describe(e.ValidateStructGroup(i, "create")))
+	assert.Equal(t, []string{
+		"ID: invalid string length",
+		"Name: empty string",
+		"Owner.Name: empty string",
+	}, describe(e.ValidateStructGroup(i, "update")))
+	assert.Equal(t, []string{"Name: empty string"}, describe(e.ValidateStructGroup(i, "delete")))
+
+	assert.True(t, errors.Is(e.ValidateStructGroup(i, "bad group"), ErrInvalidName))
+	assert.Equal(t, ErrStructNotRegister, New().ValidateStructGroup(i, "update"))
+
+	type BadGroup struct {
+		S string `xvldt:"not_empty(); up date: len(1)"`
+	}
+	type BadRule struct {
+		S string `xvldt:"not_empty(); update: mx(1)"`
+	}
+	_, err := e.CompileStruct(BadGroup{})
+	assert.True(t, errors.Is(err, ErrInvalidValidatorSyntax))
+	_, err = e.CompileStruct(BadRule{})
+	var te TagError
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, 21, te.Offset)
+	assert.True(t, errors.Is(err, ErrUnknownValidator))
+}
+
+type partialItem struct {
+	SKU string `xvldt:"not_empty()"`
+	Qty int    `xvldt:"min(1)"`
+}
+
+type partialOrder struct {
+	ID      string `xvldt:"len(3)"`
+	Address *struct {
+		City string `xvldt:"not_empty()"`
+		Zip  string `xvldt:"len(5)"`
+	} `xvldt:"required(), strct()"`
+	Items []partialItem `xvldt:"each()"`
+}
+
+func (o partialOrder) XValidate() error {
+	return errors.New("hook called")
+}
+
+func TestPartialValidation(t *testing.T) {
+	e := New(WithAutoCompile(), WithAllErrors())
+	o := partialOrder{
+		ID:    "x",
+		Items: []partialItem{{SKU: "a"}, {Qty: 1}},
+	}
+	assert.Equal(t, []string{
+		"ID: invalid string length",
+		"Address: required",
+		"Items[0].Qty: out of range",
+		"Items[1].SKU: empty string",
+		": hook called",
+	}, describe(e.ValidateStruct(o)))
+
+	assert.Equal(t, []string{"ID: invalid string length"}, describe(e.ValidateFields(o, "ID")))
+	assert.Equal(t, []string{
+		"Items[1].SKU: empty string",
+	}, describe(e.ValidateFields(o, "Items.SKU")))
+	assert.Equal(t, []string{
+		"Items[0].Qty: out of range",
+		"Items[1].SKU: empty string",
+	}, describe(e.ValidateFields(o, "Items.SKU", "Items")))
+	assert.Equal(t, []string{"Address: required"}, describe(e.ValidateFields(o, "Address.Zip")))
+	assert.Nil(t, e.ValidateFields(o))
+
+	o.Address = &struct {
+		City string `xvldt:"not_empty()"`
+		Zip  string `xvldt:"len(5)"`
+	}{Zip: "1"}
+	assert.Equal(t, []string{"Address.Zip: invalid string length"}, describe(e.ValidateFields(&o, "Address.Zip")))
+	assert.Equal(t, []string{
+		"ID: invalid string length",
+		"Address.City: empty string",
+		"Items[0].Qty: out of range",
+	}, describe(e.ValidateExcept(o, "Address.Zip", "Items.SKU")))
+	assert.Equal(t, []string{
+		"ID: invalid string length",
+		"Address.City: empty string",
+		"Address.Zip: invalid string length",
+		"Items[0].Qty: out of range",
+		"Items[1].SKU: empty string",
+		": hook called",
+	}, describe(e.ValidateExcept(o)))
+
+	assert.True(t, errors.Is(e.ValidateFields(o, "Address.Street"), ErrUnknownField))
+	assert.True(t, errors.Is(e.ValidateFields(o, "ID.Len"), ErrUnknownField))
+	assert.True(t, errors.Is(e.ValidateExcept(o, "Items[0]"), ErrInvalidArgument))
+}
+
+func TestValidateVar(t *testing.T) {
+	e := New(WithAutoCompile())
+	assert.Nil(t, e.ValidateVar("abc", "not_empty(), len(3)"))
+	assert.Nil(t, e.ValidateVar(10, "min(1), max(10)"))
+	assert.Nil(t, e.ValidateVar([]string{"a"}, "each(not_empty())"))
+	assert.Nil(t, e.ValidateVar((*string)(nil), "omitempty(), len(3)"))
+
+	err := e.ValidateVar("ab", "not_empty(), len(3)")
+	assert.Equal(t, ValidatorError{Reason: "invalid string length"}, err)
+	assert.Equal(t, "validate fail: out of range", e.ValidateVar(-1, "max(5)").Error())
+	err = e.ValidateNamedVar("id", "", "not_empty(), len(3)")
+	assert.Equal(t, ValidatorError{Reason: "empty string", FieldName: "id", Path: Path{fieldSegment("id")}}, err)
+	assert.Equal(t, []string{"[1]: out of range"}, describe(e.ValidateVar([]int{1, 11}, "each(max(10))")))
+	assert.Equal(t, []string{": required"}, describe(e.ValidateVar(nil, "required()")))
+
+	var ve ValidatorError
+	err = e.ValidateVar([]interface{}{1}, "each(srange('a'))")
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, "not a string", ve.Reason)
+
+	e = New(WithAllErrors())
+	assert.Equal(t, []string{"port: out of range", "port: invalid value"},
+		describe(e.ValidateNamedVar("port", 70000, "max(65535), irange(80, 443)")))
+
+	type Item struct {
+		SKU string `xvldt:"not_empty()"`
+	}
+	assert.True(t, errors.Is(e.ValidateVar(Item{}, "strct()"), ErrStructNotRegister))
+	assert.Nil(t, e.RegisterStruct(Item{}))
+	assert.Equal(t, []string{"[0].SKU: empty string"}, describe(e.ValidateVar([]Item{{}}, "each()")))
+
+	for rules, target := range map[string]error{
+		"mx(1)":             ErrUnknownValidator,
+		"max(1":             ErrInvalidValidatorSyntax,
+		"len(1)":            ErrInvalidArgument,
+		"eqfield(A)":        ErrInvalidArgument,
+		"required_if(A, 1)": ErrInvalidArgument,
+	} {
+		err := e.ValidateVar(1, rules)
+		var te TagError
+		assert.True(t, errors.As(err, &te), rules)
+		assert.True(t, errors.Is(err, target), rules)
+	}
+
+	// the cache of the rules is bounded
+	e = New()
+	for i := 0; i < maxVars+10; i++ {
+		assert.Nil(t, e.ValidateVar(i, "max("+strconv.Itoa(i)+")"))
+	}
+	assert.NotNil(t, e.ValidateVar(maxVars+10, "max("+strconv.Itoa(maxVars+9)+")"))
+	n := 0
+	e.vars.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, maxVars, n)
+}
+
+func TestSchema(t *testing.T) {
+	e := New()
+	s, err := e.CompileSchema(map[string]string{
+		"id":           "string(), len(3)",
+		"user.email":   "string(), not_empty()",
+		"user.age":     "int(), omitempty(), max(150)",
+		"items":        "array(), required()",
+		"items[*].sku": "string(), not_empty()",
+		"items[*].qty": "number(), min(1)",
+		"labels.*":     "string(), regex('^[a-z]+$')",
+		"note":         "omitempty(), srange('a', 'b')",
+	}, WithUnknownKeys())
+	assert.Nil(t, err)
+
+	doc := map[string]interface{}{
+		"id":   "abc",
+		"user": map[string]interface{}{"email": "e", "age": float64(20)},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a", "qty": float64(1)},
+		},
+		"labels": map[string]interface{}{"env": "prod"},
+	}
+	assert.Nil(t, s.Validate(doc))
+	assert.Nil(t, s.ValidateAll(doc))
+
+	doc = map[string]interface{}{
+		"id":   1,
+		"user": map[string]interface{}{"age": 20.5, "name": "n"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "", "qty": float64(0)},
+			"x",
+		},
+		"labels": map[string]interface{}{"Env": "prod", "tier": 1},
+		"extra":  true,
+		"note":   nil,
+	}
+	err = s.ValidateAll(doc)
+	assert.Equal(t, []string{
+		"extra: unknown key",
+		"id: must be a string, got number",
+		"items[0].qty: out of range",
+		"items[0].sku: empty string",
+		"items[1]: must be an object, got string",
+		"labels.tier: must be a string, got number",
+		"user.age: must be an integer, got number",
+		"user.name: unknown key",
+		"user.email: missing key",
+	}, describe(err))
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	assert.True(t, errors.Is(err, ErrMissingKey))
+	assert.True(t, errors.Is(err, ErrTypeMismatch))
+	var es ValidationErrors
+	assert.True(t, errors.As(err, &es))
+	assert.Equal(t, "/user/email", es[len(es)-1].Path.JSONPointer())
+	assert.Equal(t, "email", es[len(es)-1].FieldName)
+
+	assert.Equal(t, []string{"extra: unknown key"}, describe(s.Validate(doc)))
+	assert.Equal(t, []string{
+		"id: missing key",
+		"items: missing key",
+		"user: missing key",
+	}, describe(s.ValidateAll(map[string]interface{}{})))
+	assert.Equal(t, []string{": must be an object, got array"}, describe(s.Validate([]interface{}{})))
+
+	assert.Nil(t, e.ValidateMap([]interface{}{"a", "b"}, map[string]string{"[*]": "string(), len(1)"}))
+	assert.Equal(t, []string{"[1]: invalid string length"},
+		describe(e.ValidateMap([]interface{}{"a", "bc"}, map[string]string{"[*]": "string(), len(1)"})))
+
+	for rules, target := range map[string]error{
+		"string(), mx(1)": ErrUnknownValidator,
+		"int(), len(1)":   ErrInvalidArgument,
+		"string(1)":       ErrInvalidArgument,
+		"eqfield(a)":      ErrInvalidArgument,
+	} {
+		_, err := e.CompileSchema(map[string]string{"a": rules})
+		var te TagError
+		assert.True(t, errors.As(err, &te), rules)
+		assert.Equal(t, "a", te.Field)
+		assert.True(t, errors.Is(err, target), rules)
+	}
+	_, err = e.CompileSchema(map[string]string{"a..b": "string()"})
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+}
+
+func TestFieldNameResolver(t *testing.T) {
+	type Address struct {
+		City string `json:"city" xvldt:"not_empty()"`
+	}
+	type User struct {
+		Email   string    `json:"email,omitempty" xvldt:"label('Email address'), not_empty()"`
+		Age     int       `json:"-" xvldt:"min(18)"`
+		Nick    string    `json:",omitempty" form:"nick" xvldt:"not_empty()"`
+		Address *Address  `json:"address" xvldt:"strct()"`
+		Tags    []string  `json:"tags" xvldt:"label('Tags'), each(not_empty())"`
+		Items   []Address `json:"items" xvldt:"each()"`
+	}
+	u := User{Address: &Address{}, Tags: []string{""}, Items: []Address{{}}}
+	var es ValidationErrors
+
+	e := New(WithAutoCompile(), WithAllErrors(), WithNameResolver(JSONName))
+	assert.True(t, errors.As(e.ValidateStruct(u), &es))
+	assert.Equal(t, []string{
+		"email: empty string",
+		"Age: out of range",
+		"Nick: empty string",
+		"address.city: empty string",
+		"tags[0]: empty string",
+		"items[0].city: empty string",
+	}, describe(es))
+	names := make([]string, 0, len(es))
+	for _, e := range es {
+		names = append(names, e.FieldName)
+	}
+	assert.Equal(t, []string{"Email address", "Age", "Nick", "city", "Tags", "city"}, names)
+	// the labels are shown in the messages
+	assert.Equal(t, "validate fail for field Email address (email): empty string", es[0].Error())
+	assert.Equal(t, "validate fail for field Tags (tags[0]): empty string", es[4].Error())
+	assert.Equal(t, "validate fail for field address.city: empty string", es[3].Error())
+	assert.Equal(t, "/items/0/city", es[5].Path.JSONPointer())
+	// partial validation takes Go names
+	assert.Equal(t, []string{"tags[0]: empty string"}, describe(e.ValidateFields(u, "Tags")))
+
+	e = New(WithAutoCompile(), WithAllErrors(), WithNameResolver(FormName))
+	assert.Equal(t, "nick: empty string", describe(e.ValidateStruct(u))[2])
+	e = New(WithAutoCompile(), WithAllErrors())
+	assert.Equal(t, "Email: empty string", describe(e.ValidateStruct(u))[0])
+	assert.Equal(t, "name", TagName("xml")(reflect.StructField{Tag: `xml:"name,attr"`}))
+	assert.Equal(t, "", YAMLName(reflect.StructField{Tag: `yaml:"-"`}))
+
+	type Twice struct {
+		S string `xvldt:"label('a'), label('b')"`
+	}
+	type Nested struct {
+		S []string `xvldt:"each(label('a'))"`
+	}
+	type NoArg struct {
+		S string `xvldt:"label()"`
+	}
+	for _, strct := range []interface{}{Twice{}, Nested{}, NoArg{}} {
+		_, err := e.CompileStruct(strct)
+		var te TagError
+		assert.True(t, errors.As(err, &te), "%T", strct)
+		assert.True(t, errors.Is(err, ErrInvalidArgument), "%T", strct)
+	}
+}
+
+type genericUser struct {
+	ID    userID `xvldt:"slug()"`
+	Name  string `xvldt:"not_empty()"`
+	Email string `xvldt:"domain()"`
+}
+
+type userID string
+
+type tenantDomain struct{}
+
+func TestGeneric(t *testing.T) {
+	e := New(WithAllErrors())
+	assert.Nil(t, e.RegisterValidatorFactory("slug", Func(func(s string) error {
+		if strings.ContainsAny(s, " /") {
+			return ValidatorError{Reason: "invalid slug"}
+		}
+		return nil
+	})))
+	assert.Nil(t, e.RegisterValidatorCtx("domain", FuncCtx(func(ctx context.Context, s string) error {
+		if d, _ := ctx.Value(tenantDomain{}).(string); !strings.HasSuffix(s, "@"+d) {
+			return ValidatorError{Reason: "wrong domain"}
+		}
+		return nil
+	})))
+
+	tv, err := CompileWith[genericUser](e)
+	assert.Nil(t, err)
+	u := genericUser{ID: "a b", Email: "a@x.com"}
+	assert.Equal(t, []string{
+		"ID: invalid slug",
+		"Name: empty string",
+		"Email: wrong domain",
+	}, describe(tv.Validate(u)))
+	ctx := context.WithValue(context.Background(), tenantDomain{}, "x.com")
+	assert.Equal(t, []string{
+		"ID: invalid slug",
+		"Name: empty string",
+	}, describe(tv.ValidateCtx(ctx, u)))
+
+	ptv, err := CompileWith[*genericUser](e)
+	assert.Nil(t, err)
+	u = genericUser{ID: "a", Name: "n", Email: "a@x.com"}
+	assert.Nil(t, ptv.ValidateCtx(ctx, &u))
+
+	type Mismatch struct {
+		N int `xvldt:"slug()"`
+	}
+	_, err = CompileWith[Mismatch](e)
+	var te TagError
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, "slug", te.Validator)
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+
+	type NotEmptyInt struct {
+		N int `xvldt:"not_empty()"`
+	}
+	_, err = CompileWith[NotEmptyInt](e)
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+	_, err = Compile[int]()
+	assert.True(t, errors.Is(err, ErrInvalidValidatorArgument))
+
+	type Simple struct {
+		S string `xvldt:"not_empty()"`
+	}
+	stv, err := Compile[Simple]()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"S: empty string"}, describe(stv.ValidateAll(Simple{})))
+	assert.Equal(t, ErrStructNotRegister, Validate(Simple{}))
+	RegisterStruct(Simple{})
+	assert.Equal(t, []string{"S: empty string"}, describe(Validate(&Simple{})))
+	assert.Nil(t, Validate(Simple{S: "s"}))
+
+	var zero TypedValidator[Simple]
+	assert.Equal(t, ErrStructNotRegister, zero.Validate(Simple{}))
+	assert.Equal(t, ErrStructNotRegister, zero.ValidateCtx(ctx, Simple{}))
+	assert.Equal(t, ErrStructNotRegister, zero.ValidateAll(Simple{}))
+}
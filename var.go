@@ -0,0 +1,89 @@
+package xvalidator
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/ccbhj/xvalidator/internal"
+)
+
+// varKey is the key of the rules compiled for ValidateVar
+type varKey struct {
+	typ   reflect.Type
+	rules string
+}
+
+// maxVars is the number of rules cached for ValidateVar by an Engine, the
+// rules beyond it are compiled at every call
+const maxVars = 1024
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// ValidateVar validates a single value with rules written like a 'xvldt' tag,
+// like `not_empty(), len(36)`. The rules are compiled against the type of
+// value, and cached by rules and the type. Only the first rules are cached, so
+// the rules should be static strings rather than built for every call.
+// The validators that refer to other fields, like eqfield, cannot be used.
+// A TagError will be returned if the rules cannot be compiled, otherwise the
+// errors are ValidatorError without a field name.
+func (e *Engine) ValidateVar(value interface{}, rules string) error {
+	return e.ValidateNamedVar("", value, rules)
+}
+
+// ValidateNamedVar validates a single value like ValidateVar, name will be
+// filled into the FieldName and the Path of the errors.
+func (e *Engine) ValidateNamedVar(name string, value interface{}, rules string) error {
+	rs, err := e.varRules(reflect.TypeOf(value), rules)
+	if err != nil {
+		return err
+	}
+	r := e.newRun(context.Background(), false)
+	err = r.checkRules(rs, reflect.ValueOf(value))
+	if err == nil || r.abort != nil {
+		return r.abort
+	}
+	es := validationErrors(err)
+	if name != "" {
+		es = locate(es, fieldSegment(name)).(ValidationErrors)
+	}
+	if r.max > 0 && len(es) > r.max {
+		es = es[:r.max]
+	}
+	if _, ok := err.(ValidationErrors); !ok {
+		return es[0]
+	}
+	return es
+}
+
+// varRules return the rules compiled against typ
+func (e *Engine) varRules(typ reflect.Type, rules string) ([]rule, error) {
+	if typ == nil {
+		typ = interfaceType
+	}
+	key := varKey{typ: typ, rules: rules}
+	if rs, in := e.vars.Load(key); in {
+		return rs.([]rule), nil
+	}
+	c := &compiler{e: e, reg: e.load(), pending: make(map[reflect.Type]*compiledStruct)}
+	ft := fieldTag{field: reflect.StructField{Type: typ}, tag: rules}
+	calls, err := c.parseCalls(ft, rules, 0)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := c.compileCalls(ft, calls, internal.TypeIndirect(typ))
+	if err != nil {
+		return nil, err
+	}
+	for _, cs := range c.onDemand {
+		e.cache.LoadOrStore(structKey{typ: cs.typ}, cs)
+	}
+	if atomic.LoadInt32(&e.nvars) >= maxVars {
+		return rs, nil
+	}
+	actual, loaded := e.vars.LoadOrStore(key, rs)
+	if !loaded {
+		atomic.AddInt32(&e.nvars, 1)
+	}
+	return actual.([]rule), nil
+}
@@ -0,0 +1,492 @@
+// Package xvldtlint defines an Analyzer that checks the 'xvldt' tags of
+// struct fields, so that a bad tag is reported by go vet instead of
+// RegisterStruct at startup.
+//
+// The tags are parsed with the grammar of xvalidator, the Analyzer reports
+// syntax errors, unknown validators and constants, bad arguments of the
+// builtin validators, and the validators applied to values of a wrong kind.
+// Validators and constants are known if they are builtin or registered with
+// a constant name in the package or its dependencies, or named by the
+// -validators and -consts flags. If none is registered there, the names are
+// likely registered by a package that is not a dependency, like main, and
+// only the names close to a known one are reported.
+//
+// xvldtlint is a module of its own, so that the users of xvalidator do not
+// depend on golang.org/x/tools. It requires a tagged release of xvalidator,
+// the go.work file in its directory uses the xvalidator of the repository
+// during development.
+package xvldtlint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/ccbhj/xvalidator/internal"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const xvalidatorPath = "github.com/ccbhj/xvalidator"
+
+// Analyzer checks the xvldt tags of struct fields
+var Analyzer = &analysis.Analyzer{
+	Name:      "xvldt",
+	Doc:       "check the xvldt tags of struct fields",
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	Run:       run,
+	FactTypes: []analysis.Fact{new(registered)},
+}
+
+var (
+	tagName    string
+	validators string
+	consts     string
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&tagName, "tag", "xvldt", "the tag key of the rules")
+	Analyzer.Flags.StringVar(&validators, "validators", "", "comma-separated list of the validators registered elsewhere")
+	Analyzer.Flags.StringVar(&consts, "consts", "", "comma-separated list of the constants registered elsewhere")
+}
+
+// registered is the fact of the validators and constants registered in a
+// package
+type registered struct {
+	Validators []string
+	ConstInts  []string
+	ConstStrs  []string
+}
+
+func (*registered) AFact() {}
+
+func (r *registered) String() string {
+	return fmt.Sprintf("registered(%s; %s; %s)", strings.Join(r.Validators, ","),
+		strings.Join(r.ConstInts, ","), strings.Join(r.ConstStrs, ","))
+}
+
+// registerFuncs maps the functions and methods of xvalidator that register a
+// name to the list the name goes to
+var registerFuncs = map[string]func(r *registered) *[]string{
+	"RegisterValidator":        func(r *registered) *[]string { return &r.Validators },
+	"RegisterValidatorFactory": func(r *registered) *[]string { return &r.Validators },
+	"RegisterValidatorCtx":     func(r *registered) *[]string { return &r.Validators },
+	"RegisterConstInt":         func(r *registered) *[]string { return &r.ConstInts },
+	"RegisterConstStr":         func(r *registered) *[]string { return &r.ConstStrs },
+}
+
+// scope holds the names visible to a package
+type scope struct {
+	validators map[string]bool
+	constInts  map[string]bool
+	constStrs  map[string]bool
+	dynamic    bool // the package registers a name that is not constant
+	// registrations is false if no name is registered in the package, its
+	// dependencies or the flags, the names are likely registered by a package
+	// that is not a dependency, like main
+	registrations bool
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	own, dynamic := findRegistered(pass, insp)
+	if len(own.Validators)+len(own.ConstInts)+len(own.ConstStrs) > 0 {
+		pass.ExportPackageFact(own)
+	}
+	sc := newScope(pass, own)
+	sc.dynamic = dynamic
+
+	insp.Preorder([]ast.Node{(*ast.StructType)(nil)}, func(n ast.Node) {
+		st := n.(*ast.StructType)
+		for _, field := range st.Fields.List {
+			if field.Tag == nil {
+				continue
+			}
+			tag, ok := lookupTag(field.Tag, tagName)
+			if !ok {
+				continue
+			}
+			c := &checker{pass: pass, scope: sc, tag: tag}
+			c.checkTag(pass.TypesInfo.TypeOf(field.Type))
+		}
+	})
+	return nil, nil
+}
+
+// findRegistered collects the names registered in the package of pass, and
+// report whether any name is not constant, so that the unknown names cannot be
+// reported in the package.
+// The calls in xvalidator itself are skipped, they pass the names through.
+func findRegistered(pass *analysis.Pass, insp *inspector.Inspector) (own *registered, dynamic bool) {
+	own = new(registered)
+	if pass.Pkg.Path() == xvalidatorPath {
+		return own, false
+	}
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Pkg().Path() != xvalidatorPath || len(call.Args) == 0 {
+			return
+		}
+		list, ok := registerFuncs[fn.Name()]
+		if !ok {
+			return
+		}
+		tv := pass.TypesInfo.Types[call.Args[0]]
+		if tv.Value == nil || tv.Value.Kind() != constant.String {
+			dynamic = true
+			return
+		}
+		*list(own) = append(*list(own), constant.StringVal(tv.Value))
+	})
+	return own, dynamic
+}
+
+// newScope merges the names registered in the package of pass, in its
+// dependencies and in the flags
+func newScope(pass *analysis.Pass, own *registered) *scope {
+	sc := &scope{
+		validators: make(map[string]bool),
+		constInts:  make(map[string]bool),
+		constStrs:  make(map[string]bool),
+	}
+	add := func(r *registered) {
+		if len(r.Validators)+len(r.ConstInts)+len(r.ConstStrs) > 0 {
+			sc.registrations = true
+		}
+		for _, name := range r.Validators {
+			sc.validators[name] = true
+		}
+		for _, name := range r.ConstInts {
+			sc.constInts[name] = true
+		}
+		for _, name := range r.ConstStrs {
+			sc.constStrs[name] = true
+		}
+	}
+	add(own)
+	for _, fact := range pass.AllPackageFacts() {
+		if r, ok := fact.Fact.(*registered); ok {
+			add(r)
+		}
+	}
+	add(&registered{Validators: splitList(validators)})
+	for _, name := range splitList(consts) {
+		sc.registrations = true
+		// the kind of the constants in the flag is unknown
+		sc.constInts[name] = true
+		sc.constStrs[name] = true
+	}
+	for _, name := range knownNames() {
+		sc.validators[name] = true
+	}
+	return sc
+}
+
+func splitList(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// checker checks the tag of a field
+type checker struct {
+	pass  *analysis.Pass
+	scope *scope
+	tag   tagValue
+}
+
+// report reports a problem at offset of the tag value
+func (c *checker) report(offset int, format string, args ...interface{}) {
+	c.pass.Reportf(c.tag.at(offset), format, args...)
+}
+
+// reportFix reports a problem of tag[start:end] with a fix replacing it with
+// text, the fix is dropped if the span cannot be replaced
+func (c *checker) reportFix(start, end int, message, text string, format string, args ...interface{}) {
+	pos, endPos, ok := c.tag.span(start, end)
+	d := analysis.Diagnostic{Pos: pos, End: endPos, Message: fmt.Sprintf(format, args...)}
+	if ok {
+		d.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   message,
+			TextEdits: []analysis.TextEdit{{Pos: pos, End: endPos, NewText: []byte(text)}},
+		}}
+	}
+	c.pass.Report(d)
+}
+
+// syntaxError reports err at its offset in a string starting at base, it
+// return false if err is nil
+func (c *checker) syntaxError(err error, base int) bool {
+	if err == nil {
+		return false
+	}
+	var se *internal.SyntaxError
+	if !errors.As(err, &se) {
+		c.report(base, "invalid tag: %v", err)
+		return true
+	}
+	c.report(base+se.Offset, "invalid tag: %s", se.Msg)
+	return true
+}
+
+func (c *checker) checkTag(typ types.Type) {
+	if c.tag.value == "-" || typ == nil {
+		return
+	}
+	sections, err := internal.ParseSections(c.tag.value)
+	if c.syntaxError(err, 0) {
+		return
+	}
+	typ = indirect(typ)
+	for _, sec := range sections {
+		calls, ok := c.parseCalls(sec.Rules, sec.Offset)
+		if !ok {
+			continue
+		}
+		labels := 0
+		for _, call := range calls {
+			if call.Name == labelName {
+				labels++
+				c.checkLabel(call, labels)
+				continue
+			}
+			c.checkCall(call, typ)
+		}
+	}
+}
+
+// parseCalls parse the calls in s, which starts at base in the tag value
+func (c *checker) parseCalls(s string, base int) ([]internal.Call, bool) {
+	calls, err := internal.ParseCalls(s)
+	if c.syntaxError(err, base) {
+		return nil, false
+	}
+	for i := range calls {
+		calls[i].Offset += base
+		calls[i].ArgsOffset += base
+	}
+	return calls, true
+}
+
+func (c *checker) checkLabel(call internal.Call, n int) {
+	arg, err := internal.ParseArguments(call.Args)
+	if c.syntaxError(err, call.ArgsOffset) {
+		return
+	}
+	if n > 1 || len(arg.Strs) != 1 || len(arg.Ints)+len(arg.Vars) > 0 || arg.Strs[0] == "" {
+		c.report(call.Offset, "label() requires one string and can be used once")
+	}
+}
+
+// checkCalls checks calls against values of typ
+func (c *checker) checkCalls(calls []internal.Call, typ types.Type) {
+	for _, call := range calls {
+		if call.Name == labelName {
+			c.report(call.Offset, "label() can only be used at the top level of a field")
+			continue
+		}
+		c.checkCall(call, typ)
+	}
+}
+
+func (c *checker) checkCall(call internal.Call, typ types.Type) {
+	switch {
+	case call.Name == omitEmptyName || call.Name == requiredName:
+		if strings.TrimSpace(call.Args) != "" {
+			c.reportFix(call.ArgsOffset, call.ArgsOffset+len(call.Args), "Remove the arguments", "",
+				"%s() takes no arguments", call.Name)
+		}
+	case call.Name == eachName:
+		elem, ok := elemType(typ)
+		if !ok && !isTypeParam(typ) {
+			c.report(call.Offset, "each() requires a slice or an array, got %s", typ)
+		}
+		if ok {
+			c.checkNested(call, elem)
+		}
+	case call.Name == keysName || call.Name == valuesName:
+		m, ok := typ.Underlying().(*types.Map)
+		if !ok {
+			if !isTypeParam(typ) {
+				c.report(call.Offset, "%s() requires a map, got %s", call.Name, typ)
+			}
+			return
+		}
+		elem := m.Elem()
+		if call.Name == keysName {
+			elem = m.Key()
+		}
+		c.checkNested(call, indirect(elem))
+	case call.Name == orName || call.Name == notName || call.Name == allName:
+		calls, ok := c.parseCalls(call.Args, call.ArgsOffset)
+		if !ok {
+			return
+		}
+		if len(calls) == 0 {
+			c.report(call.Offset, "%s() requires validators", call.Name)
+		}
+		c.checkCalls(calls, typ)
+	case call.Name == whenName:
+		args, err := internal.SplitArgs(call.Args)
+		if c.syntaxError(err, call.ArgsOffset) {
+			return
+		}
+		if len(args) < 3 {
+			c.report(call.Offset, "when() requires a field, a value and validators")
+			return
+		}
+		start := args[2].Offset
+		if calls, ok := c.parseCalls(call.Args[start:], call.ArgsOffset+start); ok {
+			c.checkCalls(calls, typ)
+		}
+	case crossFieldNames[call.Name], conditionalNames[call.Name]:
+		_, err := internal.SplitArgs(call.Args)
+		c.syntaxError(err, call.ArgsOffset)
+	default:
+		c.checkValidator(call, typ)
+	}
+}
+
+// checkNested checks the calls in the arguments of call against values of
+// elem
+func (c *checker) checkNested(call internal.Call, elem types.Type) {
+	if calls, ok := c.parseCalls(call.Args, call.ArgsOffset); ok {
+		c.checkCalls(calls, elem)
+	}
+}
+
+// elemType return the element type of a slice or an array
+func elemType(typ types.Type) (types.Type, bool) {
+	switch t := typ.Underlying().(type) {
+	case *types.Slice:
+		return indirect(t.Elem()), true
+	case *types.Array:
+		return indirect(t.Elem()), true
+	}
+	return nil, false
+}
+
+// checkValidator checks the call of a registered validator
+func (c *checker) checkValidator(call internal.Call, typ types.Type) {
+	if !c.scope.validators[call.Name] {
+		if !c.scope.dynamic {
+			c.reportUnknown(call.Offset, call.Name, "validator", c.scope.validators)
+		}
+		return
+	}
+	arg, err := internal.ParseArguments(call.Args)
+	if c.syntaxError(err, call.ArgsOffset) {
+		return
+	}
+	resolved := true
+	for i, v := range arg.Vars {
+		switch {
+		case c.scope.constInts[v]:
+			arg.Ints = append(arg.Ints, 0)
+		case c.scope.constStrs[v]:
+			arg.Strs = append(arg.Strs, "")
+		default:
+			resolved = false
+			if !c.scope.dynamic {
+				known := make(map[string]bool)
+				for name := range c.scope.constInts {
+					known[name] = true
+				}
+				for name := range c.scope.constStrs {
+					known[name] = true
+				}
+				c.reportUnknown(call.ArgsOffset+arg.VarOffsets[i], v, "constant", known)
+			}
+		}
+	}
+
+	b, ok := builtins[call.Name]
+	if !ok {
+		return
+	}
+	if !isTypeParam(typ) && !(types.IsInterface(typ) && !b.strict) && !b.kinds.accept(typ) {
+		c.report(call.Offset, "%s() requires %s, got %s", call.Name, b.kinds.desc, typ)
+	}
+	if b.check != nil && resolved {
+		if msg := b.check(arg); msg != "" {
+			c.report(call.ArgsOffset, "%s", msg)
+		}
+	}
+}
+
+// reportUnknown reports an unknown name at offset, with a fix to the most
+// similar name in known. Only the likely typos are reported if no name is
+// registered where the package can see it.
+func (c *checker) reportUnknown(offset int, name, what string, known map[string]bool) {
+	if similar := mostSimilar(name, known); similar != "" {
+		c.reportFix(offset, offset+len(name), fmt.Sprintf("Replace with %s", similar), similar,
+			"unknown %s %q, did you mean %q?", what, name, similar)
+		return
+	}
+	if c.scope.registrations {
+		c.report(offset, "unknown %s %q", what, name)
+	}
+}
+
+// mostSimilar return the name in names closest to name, or "" if none is
+// close enough
+func mostSimilar(name string, names map[string]bool) string {
+	sorted := make([]string, 0, len(names))
+	for n := range names {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+	best, bestDist := "", len(name)/2+1
+	if bestDist > 3 {
+		bestDist = 3
+	}
+	for _, n := range sorted {
+		if d := distance(strings.ToLower(name), strings.ToLower(n)); d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// distance return the Levenshtein distance of a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,48 @@
+package xvldtlint
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "a", "b")
+}
+
+func TestLookupTag(t *testing.T) {
+	for lit, closing := range map[string]int{
+		"`json:\"id\" xvldt:\"srange('a'), mx(1)\"`": 2,
+		`"json:\"id\" xvldt:\"srange('a'), mx(1)\""`: 3, // at the backslash of the escaped quote
+	} {
+		v, ok := lookupTag(&ast.BasicLit{ValuePos: 1, Kind: token.STRING, Value: lit}, "xvldt")
+		assert.True(t, ok)
+		assert.Equal(t, "srange('a'), mx(1)", v.value)
+		// the position of mx in the literal
+		start := strings.Index(lit, "mx")
+		pos, end, ok := v.span(13, 15)
+		assert.True(t, ok)
+		assert.Equal(t, token.Pos(1+start), pos)
+		assert.Equal(t, token.Pos(3+start), end)
+		assert.Equal(t, token.Pos(1+len(lit)-closing), v.at(len(v.value)))
+	}
+	_, ok := lookupTag(&ast.BasicLit{ValuePos: 1, Kind: token.STRING, Value: "`json:\"id\"`"}, "xvldt")
+	assert.False(t, ok)
+
+	v, ok := lookupTag(&ast.BasicLit{ValuePos: 1, Kind: token.STRING, Value: "`xvldt:\"regex('\\\\d')\"`"}, "xvldt")
+	assert.True(t, ok)
+	assert.Equal(t, `regex('\d')`, v.value)
+	_, _, ok = v.span(7, 9)
+	assert.False(t, ok)
+}
+
+// TestAnalyzerModule runs the analyzer on the packages using the real
+// xvalidator
+func TestAnalyzerModule(t *testing.T) {
+	analysistest.Run(t, filepath.Join(analysistest.TestData(), "mod"), Analyzer, "example.com/mod/...")
+}
@@ -0,0 +1,19 @@
+// Command xvldtlint checks the xvldt tags of struct fields, see the package
+// xvldtlint for the problems reported.
+//
+//	go install github.com/ccbhj/xvalidator/xvldtlint/cmd/xvldtlint@latest
+//	xvldtlint ./...
+//
+// It can also be used as a vet tool:
+//
+//	go vet -vettool=$(which xvldtlint) ./...
+package main
+
+import (
+	"github.com/ccbhj/xvalidator/xvldtlint"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(xvldtlint.Analyzer)
+}
@@ -0,0 +1,134 @@
+package xvldtlint
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// tagValue is the value of a key in a struct tag, with the source position
+// of every byte of the value
+type tagValue struct {
+	value string
+	// pos[i] is the position of value[i], pos[len(value)] is the position of
+	// the closing quote of the value
+	pos []token.Pos
+}
+
+// at return the position of the byte at offset in the value
+func (v tagValue) at(offset int) token.Pos {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(v.pos) {
+		offset = len(v.pos) - 1
+	}
+	return v.pos[offset]
+}
+
+// span return the positions of value[start:end], ok is false if the bytes are
+// not contiguous in the source, like an escaped quote, so that they cannot be
+// replaced by a suggested fix.
+func (v tagValue) span(start, end int) (pos, endPos token.Pos, ok bool) {
+	if start < 0 || end > len(v.value) || start >= end {
+		return v.at(start), v.at(start), false
+	}
+	pos, endPos = v.pos[start], v.pos[end-1]+1
+	return pos, endPos, int(endPos-pos) == end-start
+}
+
+// lookupTag return the value of key in the tag of a struct field like
+// reflect.StructTag.Lookup
+func lookupTag(lit *ast.BasicLit, key string) (tagValue, bool) {
+	pos := make([]token.Pos, len(lit.Value))
+	for i := range pos {
+		pos[i] = lit.ValuePos + token.Pos(i)
+	}
+	tag, tagPos, ok := unquote(lit.Value, pos)
+	if !ok {
+		return tagValue{}, false
+	}
+	// the loop follows reflect.StructTag.Lookup
+	for i := 0; i < len(tag); {
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		start := i
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == start || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		name := tag[start:i]
+		i++
+
+		qstart := i
+		for i++; i < len(tag) && tag[i] != '"'; i++ {
+			if tag[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(tag) {
+			break
+		}
+		i++
+		if name != key {
+			continue
+		}
+		value, valuePos, ok := unquote(tag[qstart:i], tagPos[qstart:i])
+		if !ok {
+			return tagValue{}, false
+		}
+		return tagValue{value: value, pos: valuePos}, true
+	}
+	return tagValue{}, false
+}
+
+// unquote unquotes the Go string literal s whose bytes are at pos, the
+// positions of the bytes unquoted are returned with the position of the
+// closing quote
+func unquote(s string, pos []token.Pos) (string, []token.Pos, bool) {
+	if len(s) < 2 {
+		return "", nil, false
+	}
+	switch quote := s[0]; {
+	case quote == '`' && s[len(s)-1] == '`':
+		out := append([]token.Pos(nil), pos[1:]...)
+		return s[1 : len(s)-1], out, true
+	case quote == '"' && s[len(s)-1] == '"':
+	default:
+		return "", nil, false
+	}
+	var (
+		sb  strings.Builder
+		out = make([]token.Pos, 0, len(s))
+	)
+	for i := 1; i < len(s)-1; {
+		r, multibyte, tail, err := strconv.UnquoteChar(s[i:len(s)-1], '"')
+		if err != nil {
+			return "", nil, false
+		}
+		n := sb.Len()
+		if multibyte {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte(byte(r))
+		}
+		consumed := len(s) - 1 - i - len(tail)
+		for j := n; j < sb.Len(); j++ {
+			if consumed == sb.Len()-n {
+				// a plain character maps byte to byte
+				out = append(out, pos[i+j-n])
+			} else {
+				out = append(out, pos[i])
+			}
+		}
+		i += consumed
+	}
+	return sb.String(), append(out, pos[len(s)-1]), true
+}
@@ -0,0 +1,14 @@
+package dynamic
+
+import "github.com/ccbhj/xvalidator"
+
+// Register registers a validator whose name is unknown to the analyzer, so
+// that the unknown names in the package are not reported
+func Register(name string) {
+	xvalidator.RegisterValidator(name, nil)
+}
+
+type Item struct {
+	SKU string `xvldt:"sku()"`
+	Qty int    `xvldt:"len(1)"` // want `len\(\) requires a string, got int`
+}
@@ -0,0 +1,9 @@
+module example.com/mod
+
+go 1.18
+
+require github.com/ccbhj/xvalidator v0.0.0
+
+require github.com/pkg/errors v0.9.1 // indirect
+
+replace github.com/ccbhj/xvalidator => ../../..
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/ccbhj/xvalidator"
+
+	_ "example.com/mod/rules"
+)
+
+type Order struct {
+	ID       string `xvldt:"mx(10)"`       // want `unknown validator "mx", did you mean "max"\?`
+	Currency string `xvldt:"srange(NOPE)"` // want `unknown constant "NOPE"`
+	Slug     string `xvldt:"slug(), len(4)"`
+	Code     string `xvldt:"srange(CURRENCY)"`
+}
+
+var _ = xvalidator.ValidateStruct
@@ -0,0 +1,8 @@
+package rules // want package:`registered\(slug; ; CURRENCY\)`
+
+import "github.com/ccbhj/xvalidator"
+
+func init() {
+	xvalidator.RegisterValidator("slug", nil)
+	xvalidator.RegisterConstStr("CURRENCY", "USD")
+}
@@ -0,0 +1,37 @@
+package a
+
+import (
+	"a/internal"
+
+	_ "rules"
+)
+
+type Status string
+
+type Order struct {
+	ID       string            `json:"id" xvldt:"mx(10)"` // want `unknown validator "mx", did you mean "max"\?`
+	Code     string            `xvldt:"not_empty(), len(4), regex('^[A-Z]+$')"`
+	Slug     string            `xvldt:"slug()"`
+	Count    int               `xvldt:"regex(5)"` // want `regex\(\) requires a string, got int` `regex\(\) requires a string pattern`
+	Size     []int             `xvldt:"len(3)"`   // want `len\(\) requires a string, got \[\]int`
+	Status   int               `xvldt:"max(LIMIT), irange(1, 2)"`
+	Currency string            `xvldt:"srange(CURENCY)"`                           // want `unknown constant "CURENCY", did you mean "CURRENCY"\?`
+	Limit    int               `xvldt:"max(LIMT)"`                                 // want `unknown constant "LIMT", did you mean "LIMIT"\?`
+	Bad      string            `xvldt:"srange('a', 1x)"`                           // want `invalid tag: unexpected 'x'`
+	Open     string            `xvldt:"max(1"`                                     // want `invalid tag: unclosed '\('`
+	Opt      *string           `xvldt:"omitempty(1), not_empty()"`                 // want `omitempty\(\) takes no arguments`
+	Items    []internal.Item   `xvldt:"each(strct(), len(2))"`                     // want `len\(\) requires a string, got a/internal.Item`
+	Tags     map[string]int    `xvldt:"keys(len(2)), values(min(1), not_empty())"` // want `not_empty\(\) requires a string, got int`
+	Labels   map[string]string `xvldt:"each(len(1))"`                              // want `each\(\) requires a slice or an array, got map\[string\]string`
+	Either   string            `xvldt:"or(len(2), regx('a'))"`                     // want `unknown validator "regx", did you mean "regex"\?`
+	Named    string            `xvldt:"label('Name'), label('x')"`                 // want `label\(\) requires one string and can be used once`
+	Nested   string            `xvldt:"not(label('x'))"`                           // want `label\(\) can only be used at the top level of a field`
+	Grouped  string            `xvldt:"update: requird()"`                         // want `unknown validator "requird", did you mean "required"\?`
+	When     string            `xvldt:"when(Status, 1, len(a))"`                   // want `invalid tag: unexpected 'a'`
+	Any      interface{}       `xvldt:"not_empty(), len(1)"`                       // want `len\(\) requires a string, got interface\{\}`
+	Escaped  string            "xvldt:\"not_empty(), mx(1)\""                      // want `unknown validator "mx"`
+	Unknown  string            `xvldt:"whatever()"`                                // want `unknown validator "whatever"`
+	State    Status            `xvldt:"not_empty(), srange('a'), len(1), max(1)"`
+	Skipped  int               `xvldt:"-"`
+	Other    int               `json:"other"`
+}
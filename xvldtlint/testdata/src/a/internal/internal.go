@@ -0,0 +1,5 @@
+package internal
+
+type Item struct {
+	SKU string `xvldt:"not_empty()"`
+}
@@ -0,0 +1,9 @@
+// Package b sees no registration, the names may be registered by main
+package b
+
+type Order struct {
+	ID       string `xvldt:"slug()"`
+	Currency string `xvldt:"srange(CURRENCY)"`
+	Count    int    `xvldt:"mx(10)"` // want `unknown validator "mx", did you mean "max"\?`
+	Code     string `xvldt:"len(4"`  // want `invalid tag: unclosed '\('`
+}
@@ -0,0 +1,22 @@
+// Package xvalidator is a stub of the registration API for the tests
+package xvalidator
+
+type ValidatorArgs struct{}
+
+type Validator func(interface{}) error
+
+type Engine struct{}
+
+func New() *Engine { return &Engine{} }
+
+func (e *Engine) RegisterValidator(name string, factory func(args ValidatorArgs) Validator) error {
+	return nil
+}
+
+func (e *Engine) RegisterConstStr(name, val string) error { return nil }
+
+func RegisterValidator(name string, factory func(args ValidatorArgs) Validator) {}
+
+func RegisterConstStr(name, val string) {}
+
+func RegisterConstInt(name string, val uint64) {}
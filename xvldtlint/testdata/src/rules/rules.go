@@ -0,0 +1,11 @@
+package rules
+
+import "github.com/ccbhj/xvalidator"
+
+const SlugName = "slug"
+
+func init() {
+	xvalidator.RegisterValidator(SlugName, nil)
+	xvalidator.RegisterConstInt("LIMIT", 100)
+	xvalidator.New().RegisterConstStr("CURRENCY", "USD")
+}
@@ -0,0 +1,136 @@
+package xvldtlint
+
+import (
+	"go/types"
+	"regexp"
+
+	"github.com/ccbhj/xvalidator/internal"
+)
+
+// builtin describes a validator registered in every Engine, check return the
+// problem of the arguments of a call
+type builtin struct {
+	kinds kindSet
+	// strict rejects interfaces when the struct is compiled
+	strict bool
+	check  func(arg *internal.ArgsInfos) string
+}
+
+// kindSet report whether a validator accepts values of a type
+type kindSet struct {
+	desc   string
+	accept func(typ types.Type) bool
+}
+
+var (
+	// the string validators read any value whose kind is string, named string
+	// types included
+	stringKinds = kindSet{"a string", func(typ types.Type) bool {
+		return isBasic(typ, types.IsString)
+	}}
+	// the integer validators convert the values to uint64 at runtime
+	integerKinds = kindSet{"an integer", func(typ types.Type) bool {
+		return isBasic(typ, types.IsInteger|types.IsFloat|types.IsString)
+	}}
+	structKinds = kindSet{"a struct or a list of structs", func(typ types.Type) bool {
+		switch t := typ.Underlying().(type) {
+		case *types.Slice:
+			typ = t.Elem()
+		case *types.Array:
+			typ = t.Elem()
+		}
+		_, ok := indirect(typ).Underlying().(*types.Struct)
+		return ok
+	}}
+)
+
+var builtins = map[string]builtin{
+	"not_empty": {kinds: stringKinds},
+	"srange":    {kinds: stringKinds},
+	"len": {kinds: stringKinds, strict: true, check: func(arg *internal.ArgsInfos) string {
+		return needs(len(arg.Ints) > 0, "len() requires an integer")
+	}},
+	"regex": {kinds: stringKinds, strict: true, check: func(arg *internal.ArgsInfos) string {
+		if len(arg.Strs) == 0 {
+			return "regex() requires a string pattern"
+		}
+		if _, err := regexp.Compile(arg.Strs[0]); err != nil {
+			return "invalid regex pattern: " + err.Error()
+		}
+		return ""
+	}},
+	"max": {kinds: integerKinds, check: func(arg *internal.ArgsInfos) string {
+		return needs(len(arg.Ints) > 0, "max() requires an integer")
+	}},
+	"min": {kinds: integerKinds, check: func(arg *internal.ArgsInfos) string {
+		return needs(len(arg.Ints) > 0, "min() requires an integer")
+	}},
+	"irange": {kinds: integerKinds},
+	"strct":  {kinds: structKinds},
+}
+
+// the names compiled by xvalidator itself, see compileCalls
+const (
+	omitEmptyName = "omitempty"
+	requiredName  = "required"
+	eachName      = "each"
+	keysName      = "keys"
+	valuesName    = "values"
+	whenName      = "when"
+	labelName     = "label"
+	orName        = "or"
+	notName       = "not"
+	allName       = "all"
+)
+
+var crossFieldNames = map[string]bool{
+	"eqfield": true, "nefield": true, "gtfield": true, "gtefield": true, "ltfield": true, "ltefield": true,
+}
+
+var conditionalNames = map[string]bool{
+	"required_if": true, "required_unless": true, "required_with": true, "required_without": true,
+}
+
+// knownNames return all the names that need no registration
+func knownNames() []string {
+	names := []string{omitEmptyName, requiredName, eachName, keysName, valuesName,
+		whenName, labelName, orName, notName, allName}
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range crossFieldNames {
+		names = append(names, name)
+	}
+	for name := range conditionalNames {
+		names = append(names, name)
+	}
+	return names
+}
+
+func needs(ok bool, msg string) string {
+	if ok {
+		return ""
+	}
+	return msg
+}
+
+func isBasic(typ types.Type, info types.BasicInfo) bool {
+	b, ok := typ.Underlying().(*types.Basic)
+	return ok && b.Info()&info != 0
+}
+
+// indirect return the type typ points to, through all the pointers
+func indirect(typ types.Type) types.Type {
+	for {
+		p, ok := typ.Underlying().(*types.Pointer)
+		if !ok {
+			return typ
+		}
+		typ = p.Elem()
+	}
+}
+
+func isTypeParam(typ types.Type) bool {
+	_, ok := typ.(*types.TypeParam)
+	return ok
+}